@@ -0,0 +1,156 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 22:08:45
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:05:12
+ * @FilePath: /DeepLX/cli.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/service"
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// newCommand returns the flag set of a client subcommand together with the
+// server configuration, so the proxy, dl-session and chunking flags and the
+// config file are shared with "deeplx serve". The configuration must be
+// loaded once the flags are parsed.
+func newCommand(name, usage string) (*flag.FlagSet, *service.Config) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: deeplx %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs, service.NewConfig(fs)
+}
+
+// textsTranslator returns a document.TranslateFunc translating segments
+// directly with the default backend
+func textsTranslator(cfg *service.Config, sourceLang, targetLang string) document.TranslateFunc {
+	return func(segments []string) ([]string, error) {
+		result, err := translate.TranslateAll(context.Background(), cfg.DefaultTranslator(), translate.Request{
+			SourceLang: sourceLang,
+			TargetLang: targetLang,
+			ProxyURL:   cfg.NextProxy(),
+			DlSession:  cfg.NextDlSession(),
+		}, segments)
+		if err != nil {
+			return nil, err
+		}
+		if result.Code != http.StatusOK {
+			return nil, errors.New(result.Message)
+		}
+		return result.Texts, nil
+	}
+}
+
+// readInput reads path, or stdin when path is empty or "-"
+func readInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "" || path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	return string(data), err
+}
+
+// splitLines splits the input of a batch into one text per line
+func splitLines(input string) []string {
+	input = strings.TrimSuffix(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	return strings.Split(input, "\n")
+}
+
+// translateCommand implements "deeplx translate", translating texts given as
+// arguments, or read from a file or stdin, without starting the server
+func translateCommand(args []string) error {
+	fs, cfg := newCommand("translate", "-t LANG [options] [TEXT...]")
+	targetLang := fs.String("t", "", "set the target language")
+	sourceLang := fs.String("source", "", "set the source language, detected when empty")
+	sourceHints := fs.String("source-hints", "", "set a comma separated list of likely source languages for the detection")
+	tagHandling := fs.String("tag-handling", "", "set the tag handling, 'html', 'xml' or 'markdown'")
+	file := fs.String("f", "", "read the text from a file, - for stdin")
+	batch := fs.Bool("batch", false, "translate every line of the input separately")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	fs.Parse(args)
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+
+	if *targetLang == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	service.ApplyConfig(cfg)
+
+	// Every argument is a text of its own, otherwise the input is one text
+	// or, in batch mode, one text per line
+	texts := fs.Args()
+	if len(texts) == 0 {
+		input, err := readInput(*file)
+		if err != nil {
+			return err
+		}
+		if *batch {
+			texts = splitLines(input)
+		} else {
+			texts = []string{input}
+		}
+	}
+
+	translator := cfg.DefaultTranslator()
+	req := translate.Request{
+		SourceLang:  *sourceLang,
+		TargetLang:  *targetLang,
+		TagHandling: *tagHandling,
+		ProxyURL:    cfg.NextProxy(),
+		DlSession:   cfg.NextDlSession(),
+	}
+	if *sourceHints != "" {
+		req.SourceLangHints = strings.Split(*sourceHints, ",")
+	}
+	var result translate.DeepLXTranslationResult
+	var err error
+	if len(texts) == 1 {
+		req.Text = texts[0]
+		result, err = translator.Translate(context.Background(), req)
+		result.Texts = []string{result.Data}
+	} else {
+		result, err = translate.TranslateAll(context.Background(), translator, req, texts)
+	}
+	if err != nil {
+		return err
+	}
+	if result.Code != http.StatusOK {
+		return errors.New(result.Message)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	for _, text := range result.Texts {
+		fmt.Println(text)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 16:18:27
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 16:18:27
+ * @FilePath: /DeepLX/document/cues.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLineLength is the maximum length of a subtitle line after translation
+	DefaultLineLength = 42
+	// maxCueGroup is the maximum number of cues merged into one sentence
+	maxCueGroup = 4
+)
+
+// cueGroup is a run of consecutive cues translated as one text
+type cueGroup struct {
+	cues     []*Cue
+	dialogue bool
+}
+
+// isDialogue reports whether every line of a multi-line cue is a dialogue
+// line starting with a dash, in which case lines are translated one by one
+func isDialogue(text string) bool {
+	lines := strings.Split(text, "\n")
+	if len(lines) < 2 {
+		return false
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "–") {
+			return false
+		}
+	}
+	return true
+}
+
+// endsSentence reports whether text ends with sentence-final punctuation
+func endsSentence(text string) bool {
+	text = strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'”’»)]」』`, r)
+	})
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(".!?…。！？♪", r)
+}
+
+// flatten joins the lines of a cue into a single line
+func flatten(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// groupCues merges consecutive cues of an unfinished sentence, so that the
+// translator sees the neighbouring cues as context
+func groupCues(cues []*Cue) []cueGroup {
+	var groups []cueGroup
+	for _, cue := range cues {
+		if flatten(cue.Text) == "" {
+			continue
+		}
+		dialogue := isDialogue(cue.Text)
+		if n := len(groups); n > 0 && !dialogue {
+			last := &groups[n-1]
+			previous := last.cues[len(last.cues)-1]
+			if !last.dialogue && len(last.cues) < maxCueGroup && !endsSentence(previous.Text) {
+				last.cues = append(last.cues, cue)
+				continue
+			}
+		}
+		groups = append(groups, cueGroup{cues: []*Cue{cue}, dialogue: dialogue})
+	}
+	return groups
+}
+
+// distribute splits a translated sentence over cues in proportion to the
+// length of their source text, cutting between words when the translation
+// has spaces and between characters otherwise
+func distribute(translation string, cues []*Cue) []string {
+	var units []string
+	separator := " "
+	if strings.ContainsFunc(translation, unicode.IsSpace) {
+		units = strings.Fields(translation)
+	} else {
+		separator = ""
+		for _, r := range translation {
+			units = append(units, string(r))
+		}
+	}
+
+	total := 0
+	weights := make([]int, len(cues))
+	for i, cue := range cues {
+		weights[i] = utf8.RuneCountInString(flatten(cue.Text))
+		total += weights[i]
+	}
+
+	parts := make([]string, len(cues))
+	used, cumulative := 0, 0
+	for i := range cues {
+		cumulative += weights[i]
+		end := len(units)
+		if i < len(cues)-1 {
+			end = (len(units)*cumulative + total/2) / total
+			// Leave at least one unit for every following cue when possible
+			if maxEnd := len(units) - (len(cues) - 1 - i); end > maxEnd {
+				end = maxEnd
+			}
+			if end <= used && used < len(units) {
+				end = used + 1
+			}
+		}
+		if end < used {
+			end = used
+		}
+		parts[i] = strings.Join(units[used:end], separator)
+		used = end
+	}
+	return parts
+}
+
+// wrapLine wraps text into lines of at most maxLength characters. Text that
+// fits on two lines is split in the middle to keep the lines balanced.
+func wrapLine(text string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	words := strings.Fields(text)
+	if len(words) < 2 {
+		// No spaces to break on, cut between characters
+		var lines []string
+		runes := []rune(text)
+		for len(runes) > maxLength {
+			lines = append(lines, string(runes[:maxLength]))
+			runes = runes[maxLength:]
+		}
+		return strings.Join(append(lines, string(runes)), "\n")
+	}
+
+	if utf8.RuneCountInString(text) <= 2*maxLength+1 {
+		best, bestDiff := -1, -1
+		for i := 1; i < len(words); i++ {
+			first := utf8.RuneCountInString(strings.Join(words[:i], " "))
+			second := utf8.RuneCountInString(strings.Join(words[i:], " "))
+			if first > maxLength || second > maxLength {
+				continue
+			}
+			diff := first - second
+			if diff < 0 {
+				diff = -diff
+			}
+			if best < 0 || diff < bestDiff {
+				best, bestDiff = i, diff
+			}
+		}
+		if best > 0 {
+			return strings.Join(words[:best], " ") + "\n" + strings.Join(words[best:], " ")
+		}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > maxLength {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+// TranslateCues translates the text of cues in place. Consecutive cues of
+// the same sentence are translated together and the translation is spread
+// back over them, dialogue cues are translated line by line. Indices and
+// timings are never touched. Translated lines are wrapped to maxLineLength
+// characters, a non-positive value keeps every cue on a single line.
+func TranslateCues(cues []*Cue, fn TranslateFunc, maxLineLength int) error {
+	groups := groupCues(cues)
+
+	var segments []string
+	for _, group := range groups {
+		if group.dialogue {
+			for _, line := range strings.Split(group.cues[0].Text, "\n") {
+				segments = append(segments, strings.TrimSpace(line))
+			}
+			continue
+		}
+		texts := make([]string, len(group.cues))
+		for i, cue := range group.cues {
+			texts[i] = flatten(cue.Text)
+		}
+		segments = append(segments, strings.Join(texts, " "))
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+
+	translations, err := fn(segments)
+	if err != nil {
+		return err
+	}
+	if len(translations) != len(segments) {
+		return fmt.Errorf("expected %d translations, got %d", len(segments), len(translations))
+	}
+
+	i := 0
+	for _, group := range groups {
+		if group.dialogue {
+			lines := strings.Split(group.cues[0].Text, "\n")
+			group.cues[0].Text = strings.Join(translations[i:i+len(lines)], "\n")
+			i += len(lines)
+			continue
+		}
+		parts := []string{flatten(translations[i])}
+		if len(group.cues) > 1 {
+			parts = distribute(parts[0], group.cues)
+		}
+		for j, cue := range group.cues {
+			cue.Text = wrapLine(parts[j], maxLineLength)
+		}
+		i++
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 15:12:26
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 15:12:26
+ * @FilePath: /DeepLX/document/cues_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cues returns cues holding texts
+func cues(texts ...string) []*Cue {
+	result := make([]*Cue, len(texts))
+	for i, text := range texts {
+		result[i] = &Cue{Text: text}
+	}
+	return result
+}
+
+func TestGroupCues(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  [][]int
+	}{
+		{"finished sentences", []string{"Hello.", "How are you?"}, [][]int{{0}, {1}}},
+		{"unfinished sentence", []string{"I was going", "to the store", "yesterday.", "Fine!"}, [][]int{{0, 1, 2}, {3}}},
+		{"closing quote", []string{`He said "stop."`, "Then left."}, [][]int{{0}, {1}}},
+		{"group limit", []string{"one", "two", "three", "four", "five."}, [][]int{{0, 1, 2, 3}, {4}}},
+		{"dialogue", []string{"So", "- Hi.\n- Hello", "there."}, [][]int{{0}, {1}, {2}}},
+		{"blank cues skipped", []string{"Wait", " \n ", "for it."}, [][]int{{0, 2}}},
+	}
+	for _, tt := range tests {
+		input := cues(tt.texts...)
+		var got [][]int
+		for _, group := range groupCues(input) {
+			var indices []int
+			for _, cue := range group.cues {
+				for i, c := range input {
+					if c == cue {
+						indices = append(indices, i)
+					}
+				}
+			}
+			got = append(got, indices)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: groupCues() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	tests := []struct {
+		name        string
+		translation string
+		texts       []string
+		want        []string
+	}{
+		{"proportional", "eins zwei drei vier", []string{"one two", "three four"}, []string{"eins zwei", "drei vier"}},
+		{"weighted", "a b c d e f", []string{"a much longer cue", "short"}, []string{"a b c d e", "f"}},
+		{"single word cut between characters", "alles", []string{"every", "thing"}, []string{"all", "es"}},
+		{"short translation", "ja nein", []string{"xxxxxxxxxxxxxxxxxxxx", "y", "z"}, []string{"ja", "nein", ""}},
+		{"no spaces", "你好世界", []string{"hello", "world"}, []string{"你好", "世界"}},
+	}
+	for _, tt := range tests {
+		got := distribute(tt.translation, cues(tt.texts...))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: distribute(%q) = %q, want %q", tt.name, tt.translation, got, tt.want)
+		}
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"fits", "Short line", 42, "Short line"},
+		{"disabled", "A line that is far too long for any subtitle", 0, "A line that is far too long for any subtitle"},
+		{"balanced", "The quick brown fox jumps over the lazy dog", 30, "The quick brown fox\njumps over the lazy dog"},
+		{"more than two lines", "one two three four five six seven", 10, "one two\nthree four\nfive six\nseven"},
+		{"no spaces", "abcdefghij", 4, "abcd\nefgh\nij"},
+	}
+	for _, tt := range tests {
+		got := wrapLine(tt.text, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("%s: wrapLine(%q, %d) = %q, want %q", tt.name, tt.text, tt.maxLength, got, tt.want)
+		}
+		if tt.maxLength > 0 {
+			for _, line := range strings.Split(got, "\n") {
+				if len([]rune(line)) > tt.maxLength {
+					t.Errorf("%s: line %q is longer than %d", tt.name, line, tt.maxLength)
+				}
+			}
+		}
+	}
+}
+
+func TestTranslateCues(t *testing.T) {
+	input := cues("I was going", "home.", "- Hi.\n- Hello.")
+	var segments []string
+	err := TranslateCues(input, func(texts []string) ([]string, error) {
+		segments = texts
+		return []string{"Ich ging nach Hause.", "- Hallo.", "- Guten Tag."}, nil
+	}, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"I was going home.", "- Hi.", "- Hello."}; !reflect.DeepEqual(segments, want) {
+		t.Errorf("segments = %q, want %q", segments, want)
+	}
+	var got []string
+	for _, cue := range input {
+		got = append(got, cue.Text)
+	}
+	if want := []string{"Ich ging nach", "Hause.", "- Hallo.\n- Guten Tag."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cues = %q, want %q", got, want)
+	}
+}
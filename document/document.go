@@ -0,0 +1,156 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 13:45:31
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 13:45:31
+ * @FilePath: /DeepLX/document/document.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TranslateFunc translates a batch of segments and returns the translations in the same order
+type TranslateFunc func(segments []string) ([]string, error)
+
+// translators maps a file extension to the function that translates documents of that format
+var translators = map[string]func(data []byte, fn TranslateFunc) ([]byte, error){
+	"txt":      translatePlainText,
+	"html":     translateHTML,
+	"htm":      translateHTML,
+	"md":       translateMarkdown,
+	"markdown": translateMarkdown,
+	"srt":      translateSubtitle,
+	"vtt":      translateSubtitle,
+	"docx":     translateDOCX,
+}
+
+// FormatOf returns the lower-case extension of filename without the dot
+func FormatOf(filename string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// Supported reports whether documents of the given format can be translated
+func Supported(format string) bool {
+	_, ok := translators[format]
+	return ok
+}
+
+// Translate extracts the translatable segments of a document, translates
+// them with fn and reassembles a document of the same format. It returns the
+// translated document and the number of characters sent for translation.
+func Translate(format string, data []byte, fn TranslateFunc) ([]byte, int, error) {
+	translator, ok := translators[format]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported document format: %s", format)
+	}
+
+	characters := 0
+	counted := func(segments []string) ([]string, error) {
+		for _, segment := range segments {
+			characters += utf8.RuneCountInString(segment)
+		}
+		return fn(segments)
+	}
+
+	out, err := translator(data, counted)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, characters, nil
+}
+
+// part is a piece of a document, either kept verbatim or translated
+type part struct {
+	text         string
+	translatable bool
+}
+
+// parts is a document split into verbatim and translatable pieces, in order
+type parts []part
+
+// keep appends a piece that is copied to the output unchanged
+func (p *parts) keep(s string) {
+	if s != "" {
+		*p = append(*p, part{text: s})
+	}
+}
+
+// add appends a piece to translate. Surrounding whitespace is kept verbatim
+// so that the layout of the document does not depend on the translation.
+func (p *parts) add(s string) {
+	core := strings.TrimLeftFunc(s, unicode.IsSpace)
+	p.keep(s[:len(s)-len(core)])
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	if trimmed != "" {
+		*p = append(*p, part{text: trimmed, translatable: true})
+	}
+	p.keep(core[len(trimmed):])
+}
+
+// render translates every translatable piece with fn and concatenates the
+// document, passing translations through escape when it is not nil
+func (p parts) render(fn TranslateFunc, escape func(string) string) ([]byte, error) {
+	var segments []string
+	for _, item := range p {
+		if item.translatable {
+			segments = append(segments, item.text)
+		}
+	}
+
+	var translations []string
+	if len(segments) > 0 {
+		var err error
+		translations, err = fn(segments)
+		if err != nil {
+			return nil, err
+		}
+		if len(translations) != len(segments) {
+			return nil, fmt.Errorf("expected %d translations, got %d", len(segments), len(translations))
+		}
+	}
+
+	var buf bytes.Buffer
+	i := 0
+	for _, item := range p {
+		if !item.translatable {
+			buf.WriteString(item.text)
+			continue
+		}
+		text := translations[i]
+		if escape != nil {
+			text = escape(text)
+		}
+		buf.WriteString(text)
+		i++
+	}
+	return buf.Bytes(), nil
+}
+
+var paragraphBreak = regexp.MustCompile(`\r?\n(?:[ \t]*\r?\n)+`)
+
+// translatePlainText translates a plain text document paragraph by paragraph
+func translatePlainText(data []byte, fn TranslateFunc) ([]byte, error) {
+	var p parts
+	text := string(data)
+	last := 0
+	for _, loc := range paragraphBreak.FindAllStringIndex(text, -1) {
+		p.add(text[last:loc[0]])
+		p.keep(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	p.add(text[last:])
+	return p.render(fn, nil)
+}
@@ -0,0 +1,116 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 14:40:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 14:40:52
+ * @FilePath: /DeepLX/document/docx.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	docxParagraph = regexp.MustCompile(`(?s)<w:p[ >].*?</w:p>`)
+	docxText      = regexp.MustCompile(`(?s)<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
+	docxPart      = regexp.MustCompile(`^word/(?:document|header\d*|footer\d*|footnotes|endnotes)\.xml$`)
+)
+
+// escapeXML escapes s for use as XML character data
+func escapeXML(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// translateDOCXPart translates the paragraphs of a WordprocessingML part.
+// The text of every paragraph is translated as a whole and written into its
+// first text run, the other runs of the paragraph are emptied.
+func translateDOCXPart(data []byte, fn TranslateFunc) ([]byte, error) {
+	var p parts
+	content := string(data)
+	last := 0
+	for _, para := range docxParagraph.FindAllStringIndex(content, -1) {
+		runs := docxText.FindAllStringSubmatchIndex(content[para[0]:para[1]], -1)
+		var text strings.Builder
+		for _, run := range runs {
+			text.WriteString(html.UnescapeString(content[para[0]+run[2] : para[0]+run[3]]))
+		}
+		if strings.TrimSpace(text.String()) == "" {
+			continue
+		}
+
+		for i, run := range runs {
+			p.keep(content[last : para[0]+run[0]])
+			p.keep(`<w:t xml:space="preserve">`)
+			if i == 0 {
+				p.add(text.String())
+			}
+			p.keep(`</w:t>`)
+			last = para[0] + run[1]
+		}
+	}
+	p.keep(content[last:])
+	return p.render(fn, escapeXML)
+}
+
+// translateDOCX translates the body, headers, footers and notes of a DOCX
+// document and copies every other entry of the archive unchanged
+func translateDOCX(data []byte, fn TranslateFunc) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range r.File {
+		if !docxPart.MatchString(f.Name) {
+			if err := w.Copy(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		translated, err := translateDOCXPart(content, fn)
+		if err != nil {
+			return nil, err
+		}
+
+		header := f.FileHeader
+		out, err := w.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := out.Write(translated); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 13:58:02
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 13:58:02
+ * @FilePath: /DeepLX/document/html.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"bytes"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// skippedElements are the elements whose content is never translated
+var skippedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"pre":      true,
+	"code":     true,
+	"textarea": true,
+	"template": true,
+}
+
+// translateHTML translates the text nodes of an HTML document and copies
+// all markup byte for byte
+func translateHTML(data []byte, fn TranslateFunc) ([]byte, error) {
+	var p parts
+	skipped := 0
+
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			return nil, z.Err()
+		}
+
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			if skippedElements[string(name)] {
+				if tt == html.StartTagToken {
+					skipped++
+				} else if skipped > 0 {
+					skipped--
+				}
+			}
+			p.keep(raw)
+		case html.TextToken:
+			if skipped > 0 {
+				p.keep(raw)
+			} else {
+				p.add(html.UnescapeString(raw))
+			}
+		default:
+			p.keep(raw)
+		}
+	}
+
+	return p.render(fn, html.EscapeString)
+}
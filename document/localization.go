@@ -0,0 +1,141 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 19:05:36
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 19:05:36
+ * @FilePath: /DeepLX/document/localization.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// entry is a translatable value of a localization file
+type entry struct {
+	key    string
+	source string
+	// target is the existing translation of the entry, if any
+	target string
+}
+
+// localizer parses a localization file into its entries and returns a
+// function rendering the file again with one new value per entry
+type localizer func(data []byte) ([]entry, func(values []string) ([]byte, error), error)
+
+// localizers maps a file extension to the parser of that localization format
+var localizers = map[string]localizer{
+	"json":    parseJSONLocalization,
+	"yaml":    parseYAMLLocalization,
+	"yml":     parseYAMLLocalization,
+	"po":      parsePOLocalization,
+	"pot":     parsePOLocalization,
+	"xml":     parseAndroidLocalization,
+	"strings": parseStringsLocalization,
+	"xliff":   parseXLIFFLocalization,
+	"xlf":     parseXLIFFLocalization,
+}
+
+// LocalizationSupported reports whether localization files of the given format can be translated
+func LocalizationSupported(format string) bool {
+	_, ok := localizers[format]
+	return ok
+}
+
+// TranslateLocalization translates the values of a localization file and
+// renders it again in the same format, keeping keys, order and comments.
+// Entries that are already translated, either in the file itself (gettext,
+// XLIFF) or in existing, a previous translation of the file in the same
+// format, keep their translation unless overwrite is set. It returns the
+// translated file and the number of characters sent for translation.
+func TranslateLocalization(format string, data []byte, existing []byte, overwrite bool, fn TranslateFunc) ([]byte, int, error) {
+	parse, ok := localizers[format]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported localization format: %s", format)
+	}
+
+	entries, render, err := parse(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(existing) > 0 {
+		previous, _, err := parse(existing)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid existing translation: %w", err)
+		}
+		targets := make(map[string]string, len(previous))
+		for _, e := range previous {
+			if e.target != "" {
+				targets[e.key] = e.target
+			} else if e.source != "" {
+				targets[e.key] = e.source
+			}
+		}
+		for i := range entries {
+			if target, ok := targets[entries[i].key]; ok {
+				entries[i].target = target
+			}
+		}
+	}
+
+	values := make([]string, len(entries))
+	var segments []string
+	var indices []int
+	characters := 0
+	for i, e := range entries {
+		switch {
+		case strings.TrimSpace(e.source) == "":
+			values[i] = e.source
+		case e.target != "" && !overwrite:
+			values[i] = e.target
+		default:
+			segments = append(segments, e.source)
+			indices = append(indices, i)
+			characters += utf8.RuneCountInString(e.source)
+		}
+	}
+
+	if len(segments) > 0 {
+		translations, err := fn(segments)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(translations) != len(segments) {
+			return nil, 0, fmt.Errorf("expected %d translations, got %d", len(segments), len(translations))
+		}
+		for i, index := range indices {
+			values[index] = translations[i]
+		}
+	}
+
+	out, err := render(values)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, characters, nil
+}
+
+var xmlEntity = regexp.MustCompile(`^&(?:#[0-9]+|#x[0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+
+// escapeAmpersands escapes the ampersands of s that do not start an XML
+// entity, for values kept as raw XML
+func escapeAmpersands(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '&' && !xmlEntity.MatchString(s[i:]) {
+			out.WriteString("&amp;")
+			continue
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,126 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 19:21:48
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 19:21:48
+ * @FilePath: /DeepLX/document/localization_json.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonFrame is an object or array being scanned
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+// scanJSONString returns the end of the JSON string starting at i
+func scanJSONString(data []byte, i int) (int, error) {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// parseJSONLocalization walks a nested JSON file and returns every string
+// value with its dotted key path. Values are replaced in place when
+// rendering, so that key order and formatting stay untouched.
+func parseJSONLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	if !json.Valid(data) {
+		return nil, nil, fmt.Errorf("invalid JSON file")
+	}
+
+	var entries []entry
+	var spans [][2]int
+	var stack []*jsonFrame
+
+	path := func() string {
+		parts := make([]string, len(stack))
+		for i, frame := range stack {
+			if frame.object {
+				parts[i] = frame.key
+			} else {
+				parts[i] = strconv.Itoa(frame.index)
+			}
+		}
+		return strings.Join(parts, ".")
+	}
+
+	for i := 0; i < len(data); i++ {
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch data[i] {
+		case '{':
+			stack = append(stack, &jsonFrame{object: true, expectKey: true})
+		case '[':
+			stack = append(stack, &jsonFrame{})
+		case '}', ']':
+			stack = stack[:len(stack)-1]
+		case ':':
+			top.expectKey = false
+		case ',':
+			if top.object {
+				top.expectKey = true
+			} else {
+				top.index++
+			}
+		case '"':
+			end, err := scanJSONString(data, i)
+			if err != nil {
+				return nil, nil, err
+			}
+			var value string
+			if err := json.Unmarshal(data[i:end], &value); err != nil {
+				return nil, nil, err
+			}
+			if top != nil && top.object && top.expectKey {
+				top.key = value
+			} else {
+				entries = append(entries, entry{key: path(), source: value})
+				spans = append(spans, [2]int{i, end})
+			}
+			i = end - 1
+		}
+	}
+
+	render := func(values []string) ([]byte, error) {
+		var out bytes.Buffer
+		last := 0
+		for i, span := range spans {
+			out.Write(data[last:span[0]])
+			var encoded bytes.Buffer
+			encoder := json.NewEncoder(&encoded)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(values[i]); err != nil {
+				return nil, err
+			}
+			out.Write(bytes.TrimRight(encoded.Bytes(), "\n"))
+			last = span[1]
+		}
+		out.Write(data[last:])
+		return out.Bytes(), nil
+	}
+	return entries, render, nil
+}
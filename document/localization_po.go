@@ -0,0 +1,167 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 19:58:02
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 18:44:02
+ * @FilePath: /DeepLX/document/localization_po.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var poKeyword = regexp.MustCompile(`^(msgctxt|msgid_plural|msgid|msgstr(?:\[(\d+)\])?)\s+(".*")\s*$`)
+
+// poField is a keyword of a gettext entry with its (possibly multi-line) string
+type poField struct {
+	keyword string
+	value   string
+	// first and last line of the field
+	first, last int
+}
+
+// poEntry is a gettext catalog entry
+type poEntry struct {
+	fields []*poField
+}
+
+func (e *poEntry) field(keyword string) *poField {
+	for _, f := range e.fields {
+		if f.keyword == keyword {
+			return f
+		}
+	}
+	return nil
+}
+
+// unquotePO decodes a quoted gettext string
+func unquotePO(s string) (string, error) {
+	return strconv.Unquote(s)
+}
+
+// quotePO encodes s as gettext string, one quoted line per source line
+func quotePO(s string) string {
+	quote := func(s string) string {
+		s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`, "\n", `\n`).Replace(s)
+		return `"` + s + `"`
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		return quote(lines[0])
+	}
+	quoted := []string{`""`}
+	for _, line := range lines {
+		quoted = append(quoted, quote(line))
+	}
+	return strings.Join(quoted, "\n")
+}
+
+// parsePOLocalization parses a gettext PO file. Every msgstr is an entry
+// keyed by context and msgid, the plural forms translate msgid_plural. The
+// header entry is left alone, only msgstr lines change when rendering.
+func parsePOLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var entries []*poEntry
+	var current *poEntry
+	var field *poField
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			current, field = nil, nil
+		case strings.HasPrefix(trimmed, "#"):
+			if field != nil {
+				// Comments start a new entry
+				current, field = nil, nil
+			}
+		case strings.HasPrefix(trimmed, `"`):
+			if field == nil {
+				return nil, nil, fmt.Errorf("line %d: string without keyword", i+1)
+			}
+			value, err := unquotePO(trimmed)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			field.value += value
+			field.last = i
+		default:
+			match := poKeyword.FindStringSubmatch(trimmed)
+			if match == nil {
+				return nil, nil, fmt.Errorf("line %d: unexpected %q", i+1, trimmed)
+			}
+			value, err := unquotePO(match[3])
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if current == nil || (match[1] == "msgctxt" || match[1] == "msgid") && current.field("msgid") != nil {
+				current = &poEntry{}
+				entries = append(entries, current)
+			}
+			field = &poField{keyword: match[1], value: value, first: i, last: i}
+			current.fields = append(current.fields, field)
+		}
+	}
+
+	var result []entry
+	var targets []*poField
+	for _, e := range entries {
+		msgid := e.field("msgid")
+		if msgid == nil || msgid.value == "" {
+			continue
+		}
+		key := msgid.value
+		if ctxt := e.field("msgctxt"); ctxt != nil {
+			key = ctxt.value + "\x04" + key
+		}
+		plural := e.field("msgid_plural")
+		for _, f := range e.fields {
+			if !strings.HasPrefix(f.keyword, "msgstr") {
+				continue
+			}
+			source := msgid.value
+			if plural != nil && f.keyword != "msgstr[0]" {
+				source = plural.value
+			}
+			result = append(result, entry{key: key + "\x00" + f.keyword, source: source, target: f.value})
+			targets = append(targets, f)
+		}
+	}
+
+	render := func(values []string) ([]byte, error) {
+		replaced := make(map[int]*poField, len(targets))
+		translations := make(map[*poField]string, len(targets))
+		for i, f := range targets {
+			replaced[f.first] = f
+			translations[f] = values[i]
+		}
+
+		var out []string
+		for i := 0; i < len(lines); i++ {
+			f, ok := replaced[i]
+			if !ok || translations[f] == f.value {
+				// Unchanged strings keep their source lines
+				out = append(out, lines[i])
+				continue
+			}
+			indent := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " \t"))]
+			quoted := strings.ReplaceAll(quotePO(translations[f]), "\n", "\n"+indent)
+			out = append(out, indent+f.keyword+" "+quoted)
+			i = f.last
+		}
+		return []byte(strings.Join(out, "\n")), nil
+	}
+	return result, render, nil
+}
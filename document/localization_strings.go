@@ -0,0 +1,93 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 20:37:09
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 20:37:09
+ * @FilePath: /DeepLX/document/localization_strings.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	stringsPair    = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*=\s*)"((?:[^"\\]|\\.)*)"(\s*;)`)
+	stringsDecoder = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t", `\r`, "\r")
+	stringsEncoder = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+)
+
+// parseStringsLocalization parses an iOS/macOS .strings file of
+// "key" = "value"; pairs, keyed by their key. Comments are skipped when
+// looking for pairs and copied unchanged.
+func parseStringsLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	content := string(data)
+	bom := strings.HasPrefix(content, "\ufeff")
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	masked := blankComments(content)
+
+	var entries []entry
+	var spans [][2]int
+	for _, match := range stringsPair.FindAllStringSubmatchIndex(masked, -1) {
+		entries = append(entries, entry{
+			key:    stringsDecoder.Replace(content[match[2]:match[3]]),
+			source: stringsDecoder.Replace(content[match[6]:match[7]]),
+		})
+		spans = append(spans, [2]int{match[6], match[7]})
+	}
+
+	render := func(values []string) ([]byte, error) {
+		var out strings.Builder
+		if bom {
+			out.WriteString("\ufeff")
+		}
+		last := 0
+		for i, span := range spans {
+			out.WriteString(content[last:span[0]])
+			out.WriteString(stringsEncoder.Replace(values[i]))
+			last = span[1]
+		}
+		out.WriteString(content[last:])
+		return []byte(out.String()), nil
+	}
+	return entries, render, nil
+}
+
+// blankComments replaces the /* */ and // comments outside of strings with
+// spaces, keeping every offset of content
+func blankComments(content string) string {
+	out := []byte(content)
+	for i := 0; i < len(out); i++ {
+		switch {
+		case out[i] == '"':
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				end = len(out)
+			} else {
+				end += i + 4
+			}
+			for ; i < end; i++ {
+				out[i] = ' '
+			}
+			i--
+		case strings.HasPrefix(content[i:], "//"):
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,113 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 20:16:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 20:16:40
+ * @FilePath: /DeepLX/document/localization_strings_xml.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	androidResource = regexp.MustCompile(`(?s)<(string-array|plurals|string)\b([^>]*?)(/>|>(.*?)</(?:string|string-array|plurals)>)`)
+	androidItem     = regexp.MustCompile(`(?s)<item\b([^>]*?)>(.*?)</item>`)
+	androidName     = regexp.MustCompile(`\bname\s*=\s*"([^"]*)"`)
+	androidQuantity = regexp.MustCompile(`\bquantity\s*=\s*"([^"]*)"`)
+	androidNoTrans  = regexp.MustCompile(`\btranslatable\s*=\s*"false"`)
+	androidDecoder  = strings.NewReplacer(`\'`, `'`, `\"`, `"`, `\n`, "\n", `\t`, "\t", `\@`, `@`, `\?`, `?`, `\\`, `\`)
+	androidEncoder  = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+)
+
+// unescapeAndroid decodes the backslash escapes of an Android string
+// resource. Markup and XML entities are kept as they are.
+func unescapeAndroid(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) && !strings.HasSuffix(s, `\"`) {
+		s = s[1 : len(s)-1]
+	}
+	return androidDecoder.Replace(s)
+}
+
+// escapeAndroid encodes s as Android string resource
+func escapeAndroid(s string) string {
+	s = androidEncoder.Replace(s)
+	if strings.HasPrefix(s, "@") || strings.HasPrefix(s, "?") {
+		s = `\` + s
+	}
+	return s
+}
+
+// parseAndroidLocalization parses an Android strings.xml resource file.
+// Strings are keyed by name, array items by name[index] and plural items by
+// name#quantity. Resources marked translatable="false" and references to
+// other resources are left alone.
+func parseAndroidLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	content := string(data)
+	if !strings.Contains(content, "<resources") {
+		return nil, nil, fmt.Errorf("invalid Android resource file")
+	}
+
+	var entries []entry
+	var spans [][2]int
+	add := func(key string, start, end int) {
+		raw := content[start:end]
+		trimmed := strings.TrimSpace(raw)
+		if strings.HasPrefix(trimmed, "@") || strings.HasPrefix(trimmed, "?") {
+			return
+		}
+		entries = append(entries, entry{key: key, source: unescapeAndroid(raw)})
+		spans = append(spans, [2]int{start, end})
+	}
+
+	for _, match := range androidResource.FindAllStringSubmatchIndex(content, -1) {
+		if match[8] < 0 {
+			continue
+		}
+		kind := content[match[2]:match[3]]
+		attrs := content[match[4]:match[5]]
+		if androidNoTrans.MatchString(attrs) {
+			continue
+		}
+		name := androidName.FindStringSubmatch(attrs)
+		if name == nil {
+			continue
+		}
+
+		if kind == "string" {
+			add(name[1], match[8], match[9])
+			continue
+		}
+
+		body := content[match[8]:match[9]]
+		for i, item := range androidItem.FindAllStringSubmatchIndex(body, -1) {
+			key := fmt.Sprintf("%s[%d]", name[1], i)
+			if quantity := androidQuantity.FindStringSubmatch(body[item[2]:item[3]]); quantity != nil {
+				key = name[1] + "#" + quantity[1]
+			}
+			add(key, match[8]+item[4], match[8]+item[5])
+		}
+	}
+
+	render := func(values []string) ([]byte, error) {
+		var out strings.Builder
+		last := 0
+		for i, span := range spans {
+			out.WriteString(content[last:span[0]])
+			out.WriteString(escapeAmpersands(escapeAndroid(values[i])))
+			last = span[1]
+		}
+		out.WriteString(content[last:])
+		return []byte(out.String()), nil
+	}
+	return entries, render, nil
+}
@@ -0,0 +1,210 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 18:41:09
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 18:41:09
+ * @FilePath: /DeepLX/document/localization_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+// localizationSamples are translated files of every localization format
+var localizationSamples = map[string]string{
+	"json": `{
+  "title": "Hello",
+  "nested": {"items": ["One", "Two \"quoted\""], "count": 3},
+  "html": "<b>&</b>"
+}
+`,
+	"yaml": `# Greetings
+title: Hello # inline comment
+quoted: "Say \"hi\""
+single: 'It''s here'
+list:
+  - First
+  - Second: with colon
+block: |
+  Line one
+  Line two
+folded: >-
+  Folded
+  text
+count: 3
+flag: true
+`,
+	"po": `msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#: main.c:1
+msgid "Hello"
+msgstr "Hallo"
+
+msgctxt "menu"
+msgid "File"
+msgstr "Datei"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Eine Datei"
+msgstr[1] ""
+"%d Dateien"
+`,
+	"xml": `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <!-- App name -->
+    <string name="app">My &amp; App</string>
+    <string name="fixed" translatable="false">ID</string>
+    <string-array name="days">
+        <item>Monday</item>
+        <item>@string/app</item>
+    </string-array>
+    <plurals name="files">
+        <item quantity="one">%d file</item>
+        <item quantity="other">%d files</item>
+    </plurals>
+</resources>
+`,
+	"strings": `/* Title */
+"title" = "Hello";
+// Comment
+"quote" = "Say \"hi\"\n";
+`,
+	"xliff": `<?xml version="1.0"?>
+<xliff version="1.2">
+  <file source-language="en" target-language="de">
+    <body>
+      <trans-unit id="a">
+        <source>Hello <b>world</b></source>
+        <target>Hallo <b>Welt</b></target>
+      </trans-unit>
+      <trans-unit id="b">
+        <source>Bye</source>
+        <target>Tschüss</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+}
+
+func TestLocalizationRoundTrip(t *testing.T) {
+	for format, sample := range localizationSamples {
+		entries, render, err := localizers[format]([]byte(sample))
+		if err != nil {
+			t.Errorf("%s: parse error = %v", format, err)
+			continue
+		}
+		if len(entries) == 0 {
+			t.Errorf("%s: no entries", format)
+			continue
+		}
+		values := make([]string, len(entries))
+		for i, e := range entries {
+			values[i] = e.source
+			if e.target != "" {
+				values[i] = e.target
+			}
+		}
+		out, err := render(values)
+		if err != nil {
+			t.Errorf("%s: render error = %v", format, err)
+			continue
+		}
+		if string(out) != sample {
+			t.Errorf("%s: render() changed the file:\n%s", format, out)
+		}
+	}
+}
+
+// tag "translates" texts by tagging them
+func tag(texts []string) ([]string, error) {
+	translations := make([]string, len(texts))
+	for i, text := range texts {
+		translations[i] = "DE:" + text
+	}
+	return translations, nil
+}
+
+func TestTranslateLocalization(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		want   string
+	}{
+		{"json", `{"a": "Hi", "b": ["Yo"], "n": 1}`, `{"a": "DE:Hi", "b": ["DE:Yo"], "n": 1}`},
+		{"strings", "\"a\" = \"Hi\";\n", "\"a\" = \"DE:Hi\";\n"},
+		{"po", "msgid \"Hi\"\nmsgstr \"\"\n", "msgid \"Hi\"\nmsgstr \"DE:Hi\"\n"},
+		{"xml", "<resources>\n  <string name=\"a\">Hi &amp; bye</string>\n</resources>\n", "<resources>\n  <string name=\"a\">DE:Hi &amp; bye</string>\n</resources>\n"},
+		{"xliff", "<xliff version=\"1.2\"><file><body><trans-unit id=\"a\"><source>Hi</source></trans-unit></body></file></xliff>",
+			"<xliff version=\"1.2\"><file><body><trans-unit id=\"a\"><source>Hi</source><target>DE:Hi</target></trans-unit></body></file></xliff>"},
+	}
+	for _, tt := range tests {
+		out, characters, err := TranslateLocalization(tt.format, []byte(tt.input), nil, false, tag)
+		if err != nil {
+			t.Errorf("%s: TranslateLocalization() error = %v", tt.format, err)
+			continue
+		}
+		if string(out) != tt.want || characters == 0 {
+			t.Errorf("%s: TranslateLocalization() = %q (%d characters), want %q", tt.format, out, characters, tt.want)
+		}
+	}
+
+	// Translated entries are kept unless overwritten
+	po := "msgid \"Hi\"\nmsgstr \"Hallo\"\n\nmsgid \"Bye\"\nmsgstr \"\"\n"
+	out, characters, _ := TranslateLocalization("po", []byte(po), nil, false, tag)
+	if want := "msgid \"Hi\"\nmsgstr \"Hallo\"\n\nmsgid \"Bye\"\nmsgstr \"DE:Bye\"\n"; string(out) != want || characters != 3 {
+		t.Errorf("po: TranslateLocalization() = %q (%d characters), want %q", out, characters, want)
+	}
+	out, _, _ = TranslateLocalization("po", []byte(po), nil, true, tag)
+	if !strings.Contains(string(out), `msgstr "DE:Hi"`) {
+		t.Errorf("po with overwrite: TranslateLocalization() = %q", out)
+	}
+}
+
+func TestTranslateYAMLKeepsFormatting(t *testing.T) {
+	out, _, err := TranslateLocalization("yaml", []byte(localizationSamples["yaml"]), nil, false, func(texts []string) ([]string, error) {
+		translations, _ := tag(texts)
+		translations[2] = "C'est ici: oui"
+		translations[5] = "Zeile eins\nZeile zwei\n"
+		return translations, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Greetings
+title: DE:Hello # inline comment
+quoted: "DE:Say \"hi\""
+single: 'C''est ici: oui'
+list:
+  - DE:First
+  - Second: DE:with colon
+block: |
+  Zeile eins
+  Zeile zwei
+folded: >-
+  DE:Folded text
+count: 3
+flag: true
+`
+	if string(out) != want {
+		t.Errorf("TranslateLocalization() =\n%s\nwant\n%s", out, want)
+	}
+
+	// Values a style cannot hold switch to double quotes
+	out, _, err = TranslateLocalization("yaml", []byte("a: plain\nb: |\n  block\n"), nil, false, func(texts []string) ([]string, error) {
+		return []string{"two\nlines", " leading space\n\n"}, nil
+	})
+	if want := "a: \"two\\nlines\"\nb: \" leading space\\n\\n\"\n"; err != nil || string(out) != want {
+		t.Errorf("TranslateLocalization() = %q, %v, want %q", out, err, want)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 20:52:31
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 20:52:31
+ * @FilePath: /DeepLX/document/localization_xliff.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	xliffUnit    = regexp.MustCompile(`(?s)<(trans-unit|unit)\b([^>]*)>(.*?)</(?:trans-unit|unit)>`)
+	xliffID      = regexp.MustCompile(`\bid\s*=\s*"([^"]*)"`)
+	xliffSource  = regexp.MustCompile(`(?s)<source\b[^>]*>(.*?)</source>`)
+	xliffTarget  = regexp.MustCompile(`(?s)<target\b[^>]*>(.*?)</target>|<target\b[^>]*/>`)
+	xliffNoTrans = regexp.MustCompile(`\btranslate\s*=\s*"no"`)
+)
+
+// xliffSegment locates a source and its (possibly missing) target in the file
+type xliffSegment struct {
+	// start and end of the target content, or the insertion point after
+	// </source> when the target is missing
+	start, end int
+	missing    bool
+	selfClosed bool
+}
+
+// parseXLIFFLocalization parses an XLIFF 1.2 or 2.0 file. Every source of a
+// trans-unit or unit is an entry keyed by the unit id, its target is the
+// existing translation. Missing targets are inserted after their source.
+// Sources are passed on as raw XML, so inline markup and entities should be
+// protected from translation.
+func parseXLIFFLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	content := string(data)
+	if !strings.Contains(content, "<xliff") {
+		return nil, nil, fmt.Errorf("invalid XLIFF file")
+	}
+
+	var entries []entry
+	var segments []xliffSegment
+	for _, unit := range xliffUnit.FindAllStringSubmatchIndex(content, -1) {
+		attrs := content[unit[4]:unit[5]]
+		if xliffNoTrans.MatchString(attrs) {
+			continue
+		}
+		id := xliffID.FindStringSubmatch(attrs)
+		if id == nil {
+			continue
+		}
+
+		body := content[unit[6]:unit[7]]
+		sources := xliffSource.FindAllStringSubmatchIndex(body, -1)
+		targets := xliffTarget.FindAllStringSubmatchIndex(body, -1)
+		for i, source := range sources {
+			key := id[1]
+			if len(sources) > 1 {
+				key = fmt.Sprintf("%s#%d", id[1], i)
+			}
+			e := entry{key: key, source: body[source[2]:source[3]]}
+
+			// The target belongs to this source if it comes before the next source
+			next := len(body)
+			if i+1 < len(sources) {
+				next = sources[i+1][0]
+			}
+			segment := xliffSegment{start: unit[6] + source[1], end: unit[6] + source[1], missing: true}
+			for _, target := range targets {
+				if target[0] < source[1] || target[0] >= next {
+					continue
+				}
+				if target[2] < 0 {
+					segment = xliffSegment{start: unit[6] + target[0], end: unit[6] + target[1], selfClosed: true}
+				} else {
+					segment = xliffSegment{start: unit[6] + target[2], end: unit[6] + target[3]}
+					e.target = body[target[2]:target[3]]
+				}
+				break
+			}
+
+			entries = append(entries, e)
+			segments = append(segments, segment)
+		}
+	}
+
+	render := func(values []string) ([]byte, error) {
+		var out strings.Builder
+		last := 0
+		for i, segment := range segments {
+			out.WriteString(content[last:segment.start])
+			value := escapeAmpersands(values[i])
+			switch {
+			case segment.missing, segment.selfClosed:
+				out.WriteString("<target>" + value + "</target>")
+			default:
+				out.WriteString(value)
+			}
+			last = segment.end
+		}
+		out.WriteString(content[last:])
+		return []byte(out.String()), nil
+	}
+	return entries, render, nil
+}
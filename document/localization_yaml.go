@@ -0,0 +1,296 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 19:40:15
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 18:05:37
+ * @FilePath: /DeepLX/document/localization_yaml.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlStrings returns the string scalars of a YAML node tree with their
+// dotted key paths
+func yamlStrings(root *yaml.Node) ([]entry, []*yaml.Node) {
+	var entries []entry
+	var nodes []*yaml.Node
+	var walk func(node *yaml.Node, path []string)
+	walk = func(node *yaml.Node, path []string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], append(path, node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, append(path, strconv.Itoa(i)))
+			}
+		case yaml.ScalarNode:
+			if node.ShortTag() == "!!str" {
+				entries = append(entries, entry{key: strings.Join(path, "."), source: node.Value})
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	walk(root, nil)
+	return entries, nodes
+}
+
+// parseYAMLLocalization walks a YAML file and returns every string scalar
+// with its dotted key path. Values are replaced in place when rendering, in
+// the quoting or block style of the original scalar, so that comments, key
+// order and formatting stay untouched. Files whose scalars cannot be located
+// are re-encoded from their node tree instead, which keeps key order and
+// comments but not the formatting.
+func parseYAMLLocalization(data []byte) ([]entry, func(values []string) ([]byte, error), error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, err
+	}
+	entries, nodes := yamlStrings(&root)
+
+	render := func(values []string) ([]byte, error) {
+		if root.Kind == 0 {
+			return data, nil
+		}
+		if out, err := replaceYAMLScalars(data, nodes, values); err == nil {
+			return out, nil
+		}
+
+		for i, node := range nodes {
+			node.Value = values[i]
+		}
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&root); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return entries, render, nil
+}
+
+// replaceYAMLScalars replaces the source text of every scalar of nodes with
+// its new value. The result is parsed again to make sure it holds the new
+// values, an error means that the file has to be re-encoded.
+func replaceYAMLScalars(data []byte, nodes []*yaml.Node, values []string) ([]byte, error) {
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	type replacement struct {
+		start, end int
+		text       string
+	}
+	var replacements []replacement
+	for i, node := range nodes {
+		// Unchanged values keep their source text, line folding included
+		if values[i] == node.Value {
+			continue
+		}
+		start, err := yamlOffset(data, lineStarts, node.Line, node.Column)
+		if err != nil {
+			return nil, err
+		}
+		end, text, err := yamlScalar(data, start, node, values[i])
+		if err != nil {
+			return nil, err
+		}
+		replacements = append(replacements, replacement{start, end, text})
+	}
+	slices.SortFunc(replacements, func(a, b replacement) int { return a.start - b.start })
+
+	var out bytes.Buffer
+	last := 0
+	for _, r := range replacements {
+		if r.start < last {
+			return nil, fmt.Errorf("overlapping scalars at offset %d", r.start)
+		}
+		out.Write(data[last:r.start])
+		out.WriteString(r.text)
+		last = r.end
+	}
+	out.Write(data[last:])
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(out.Bytes(), &root); err != nil {
+		return nil, err
+	}
+	entries, _ := yamlStrings(&root)
+	if len(entries) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(values), len(entries))
+	}
+	for i, e := range entries {
+		if e.source != values[i] {
+			return nil, fmt.Errorf("value of %s did not survive in place", e.key)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// yamlOffset returns the byte offset of a 1-based line and character column
+func yamlOffset(data []byte, lineStarts []int, line, column int) (int, error) {
+	if line < 1 || line > len(lineStarts) || column < 1 {
+		return 0, fmt.Errorf("invalid position %d:%d", line, column)
+	}
+	offset := lineStarts[line-1]
+	for n := 1; n < column; n++ {
+		if offset >= len(data) || data[offset] == '\n' {
+			return 0, fmt.Errorf("invalid position %d:%d", line, column)
+		}
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset, nil
+}
+
+// yamlScalar returns the end of the scalar starting at start and its new
+// source text holding value
+func yamlScalar(data []byte, start int, node *yaml.Node, value string) (int, string, error) {
+	switch {
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return yamlBlockScalar(data, start, node, value)
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		if data[start] != '"' {
+			return 0, "", fmt.Errorf("no double quote at offset %d", start)
+		}
+		for i := start + 1; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, yamlFlowScalar(value, yaml.DoubleQuotedStyle), nil
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		if data[start] != '\'' {
+			return 0, "", fmt.Errorf("no single quote at offset %d", start)
+		}
+		for i := start + 1; i < len(data); i++ {
+			if data[i] != '\'' {
+				continue
+			}
+			if i+1 < len(data) && data[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, yamlFlowScalar(value, yaml.SingleQuotedStyle), nil
+		}
+	case node.Style == 0:
+		// Plain scalars have no escapes, single line ones read as their value
+		end := start + len(node.Value)
+		if end <= len(data) && string(data[start:end]) == node.Value {
+			return end, yamlFlowScalar(value, 0), nil
+		}
+	}
+	return 0, "", fmt.Errorf("unsupported scalar at offset %d", start)
+}
+
+// yamlFlowScalar returns value as a scalar of style, or double-quoted when
+// the style cannot hold it on a single line
+func yamlFlowScalar(value string, style yaml.Style) string {
+	if style != yaml.DoubleQuotedStyle {
+		out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: style})
+		if text := strings.TrimSuffix(string(out), "\n"); err == nil && !strings.Contains(text, "\n") {
+			return text
+		}
+	}
+	// JSON strings are valid double-quoted YAML scalars
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// yamlBlockScalar returns the end of the literal or folded block scalar
+// whose indicator is at start, and its new source text holding value
+func yamlBlockScalar(data []byte, start int, node *yaml.Node, value string) (int, string, error) {
+	lineEnd := bytes.IndexByte(data[start:], '\n')
+	if lineEnd < 0 {
+		return 0, "", fmt.Errorf("block scalar without content at offset %d", start)
+	}
+	lineEnd += start
+	header := start
+	for header < lineEnd && !strings.ContainsRune(" \t#", rune(data[header])) {
+		header++
+	}
+	if bytes.ContainsAny(data[start:header], "0123456789") {
+		return 0, "", fmt.Errorf("block scalar with indentation indicator at offset %d", start)
+	}
+
+	// The content runs until the first line indented less than its first line
+	indent, end := -1, lineEnd
+	for i := lineEnd + 1; i < len(data); {
+		next := bytes.IndexByte(data[i:], '\n')
+		if next < 0 {
+			next = len(data) - i
+		}
+		line := data[i : i+next]
+		if trimmed := bytes.TrimLeft(line, " "); len(bytes.TrimSpace(trimmed)) > 0 {
+			lineIndent := len(line) - len(trimmed)
+			if indent < 0 {
+				indent = lineIndent
+			}
+			if lineIndent < indent {
+				break
+			}
+			end = i + next
+		}
+		i += next + 1
+	}
+	if indent <= 0 {
+		return 0, "", fmt.Errorf("block scalar without content at offset %d", start)
+	}
+
+	body := strings.TrimSuffix(value, "\n")
+	chomping := ""
+	switch {
+	case strings.HasSuffix(body, "\n"), strings.HasPrefix(body, " "), body == "":
+		// Kept trailing newlines and leading spaces need a quoted scalar
+		return end, yamlFlowScalar(value, yaml.DoubleQuotedStyle), nil
+	case body == value:
+		chomping = "-"
+	}
+	indicator := "|"
+	if node.Style&yaml.FoldedStyle != 0 && !strings.Contains(body, "\n") {
+		indicator = ">"
+	}
+
+	var out strings.Builder
+	out.WriteString(indicator + chomping)
+	out.Write(data[header:lineEnd])
+	for _, line := range strings.Split(body, "\n") {
+		out.WriteString("\n")
+		if line != "" {
+			out.WriteString(strings.Repeat(" ", indent) + line)
+		}
+	}
+	return end, out.String(), nil
+}
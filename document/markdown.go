@@ -0,0 +1,27 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 14:06:44
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 17:58:20
+ * @FilePath: /DeepLX/document/markdown.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// translateMarkdown translates the prose of a Markdown document, keeping
+// front matter, code, HTML, URLs and the block structure verbatim
+func translateMarkdown(data []byte, fn TranslateFunc) ([]byte, error) {
+	out, err := translate.TranslateMarkdownWith(string(data), fn)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
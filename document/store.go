@@ -0,0 +1,252 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 15:02:37
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 00:02:36
+ * @FilePath: /DeepLX/document/store.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job statuses, consistent with the official API
+const (
+	StatusQueued      = "queued"
+	StatusTranslating = "translating"
+	StatusDone        = "done"
+	StatusError       = "error"
+)
+
+var (
+	// ErrNotFound is returned when a job does not exist or the key does not match
+	ErrNotFound = errors.New("document not found")
+
+	jobID = regexp.MustCompile(`^[0-9A-F]{32}$`)
+)
+
+// Job is a document translation job
+type Job struct {
+	ID               string    `json:"document_id"`
+	Key              string    `json:"document_key"`
+	Filename         string    `json:"filename"`
+	Format           string    `json:"format"`
+	SourceLang       string    `json:"source_lang"`
+	TargetLang       string    `json:"target_lang"`
+	Backend          string    `json:"backend,omitempty"`
+	Status           string    `json:"status"`
+	BilledCharacters int       `json:"billed_characters"`
+	ErrorMessage     string    `json:"error_message,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Store keeps document jobs on disk. Every job is made of a metadata file,
+// the uploaded source document and, once done, the translated result.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore opens a job store in dir, creating the directory if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// randomHex returns n random bytes encoded as upper-case hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+func (s *Store) path(id, ext string) string {
+	return filepath.Join(s.dir, id+ext)
+}
+
+// Create stores an uploaded document and returns its queued job, to be
+// translated with the named backend
+func (s *Store) Create(filename, sourceLang, targetLang, backend string, data []byte) (*Job, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	key, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:         id,
+		Key:        key,
+		Filename:   filepath.Base(filename),
+		Format:     FormatOf(filename),
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Backend:    backend,
+		Status:     StatusQueued,
+		CreatedAt:  time.Now(),
+	}
+	if err := os.WriteFile(s.path(id, ".src"), data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := s.Update(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Update writes the metadata of a job
+func (s *Store) Update(job *Job) error {
+	job.UpdatedAt = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := s.path(job.ID, ".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(job.ID, ".json"))
+}
+
+// Get returns the job with the given id if key matches its document key
+func (s *Store) Get(id, key string) (*Job, error) {
+	id = strings.ToUpper(id)
+	if !jobID.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	job, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.ToUpper(key)), []byte(job.Key)) != 1 {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *Store) load(id string) (*Job, error) {
+	s.mu.Lock()
+	data, err := os.ReadFile(s.path(id, ".json"))
+	s.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// jobs returns the stored jobs for which keep returns true
+func (s *Store) jobs(keep func(job *Job) bool) ([]*Job, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	for _, match := range matches {
+		job, err := s.load(strings.TrimSuffix(filepath.Base(match), ".json"))
+		if err != nil {
+			continue
+		}
+		if keep(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Pending returns the jobs that were queued or translating, e.g. when the
+// server was stopped before they finished
+func (s *Store) Pending() ([]*Job, error) {
+	return s.jobs(func(job *Job) bool {
+		return job.Status == StatusQueued || job.Status == StatusTranslating
+	})
+}
+
+// Expire deletes the finished jobs, done or failed, last updated before
+// before and returns how many were deleted
+func (s *Store) Expire(before time.Time) (int, error) {
+	expired, err := s.jobs(func(job *Job) bool {
+		updated := job.UpdatedAt
+		if updated.IsZero() {
+			// Jobs stored before updated_at was recorded
+			updated = job.CreatedAt
+		}
+		return (job.Status == StatusDone || job.Status == StatusError) && updated.Before(before)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	deleted := 0
+	for _, job := range expired {
+		if err := s.Delete(job); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// Source returns the uploaded document of a job
+func (s *Store) Source(job *Job) ([]byte, error) {
+	return os.ReadFile(s.path(job.ID, ".src"))
+}
+
+// SaveResult stores the translated document of a job
+func (s *Store) SaveResult(job *Job, data []byte) error {
+	return os.WriteFile(s.path(job.ID, ".out"), data, 0o600)
+}
+
+// Result returns the translated document of a job
+func (s *Store) Result(job *Job) ([]byte, error) {
+	return os.ReadFile(s.path(job.ID, ".out"))
+}
+
+// Delete removes every file of a job
+func (s *Store) Delete(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, ext := range []string{".json", ".src", ".out"} {
+		if err := os.Remove(s.path(job.ID, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
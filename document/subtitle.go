@@ -0,0 +1,191 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 14:21:19
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 10:12:44
+ * @FilePath: /DeepLX/document/subtitle.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Cue is a single subtitle cue
+type Cue struct {
+	Index    string `json:"index,omitempty"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Settings string `json:"settings,omitempty"`
+	Text     string `json:"text"`
+}
+
+// subtitleBlock is a block of a subtitle file, either a cue or a block kept
+// verbatim such as the WEBVTT header, NOTE, STYLE or REGION
+type subtitleBlock struct {
+	raw string
+	cue *Cue
+}
+
+// Subtitle is a parsed SRT or WebVTT file
+type Subtitle struct {
+	Format  string
+	newline string
+	blocks  []subtitleBlock
+}
+
+// ErrEmptySubtitle is returned when a subtitle file has no content at all
+var ErrEmptySubtitle = errors.New("empty subtitle file")
+
+var (
+	subtitleBlockBreak = regexp.MustCompile(`\n(?:[ \t]*\n)+`)
+	// subtitleTimestamp matches SRT and WebVTT timestamps, whose hours are
+	// optional in WebVTT
+	subtitleTimestamp = regexp.MustCompile(`^(?:\d+:)?[0-5]?\d:[0-5]\d[,.]\d{3}$`)
+)
+
+// ParseSubtitle parses an SRT or WebVTT file. It fails with ErrEmptySubtitle
+// on blank files, and with a parse error when a timing line is invalid or
+// the file has no cue.
+func ParseSubtitle(data []byte) (*Subtitle, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	s := &Subtitle{Format: "srt", newline: "\n"}
+	if strings.Contains(text, "\r\n") {
+		s.newline = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	if strings.HasPrefix(text, "WEBVTT") {
+		s.Format = "vtt"
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptySubtitle
+	}
+
+	for _, block := range subtitleBlockBreak.Split(strings.Trim(text, "\n"), -1) {
+		lines := strings.Split(block, "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 || timing > 1 {
+			s.blocks = append(s.blocks, subtitleBlock{raw: block})
+			continue
+		}
+
+		start, rest, _ := strings.Cut(lines[timing], "-->")
+		rest = strings.TrimSpace(rest)
+		end, settings := rest, ""
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			end, settings = rest[:i], rest[i:]
+		}
+
+		start = strings.TrimSpace(start)
+		if !subtitleTimestamp.MatchString(start) || !subtitleTimestamp.MatchString(end) {
+			return nil, fmt.Errorf("invalid timing line %q", lines[timing])
+		}
+
+		cue := &Cue{
+			Start:    start,
+			End:      end,
+			Settings: settings,
+			Text:     strings.Join(lines[timing+1:], "\n"),
+		}
+		if timing == 1 {
+			cue.Index = lines[0]
+		}
+		s.blocks = append(s.blocks, subtitleBlock{cue: cue})
+	}
+
+	if len(s.Cues()) == 0 {
+		return nil, fmt.Errorf("no cue found in %s file", s.Format)
+	}
+	return s, nil
+}
+
+// ValidateCues checks that cues can be rendered, every cue being set and
+// having valid timestamps
+func ValidateCues(cues []*Cue) error {
+	for i, cue := range cues {
+		if cue == nil {
+			return fmt.Errorf("cue %d is missing", i+1)
+		}
+		if !subtitleTimestamp.MatchString(cue.Start) || !subtitleTimestamp.MatchString(cue.End) {
+			return fmt.Errorf("cue %d has invalid timestamps %q --> %q", i+1, cue.Start, cue.End)
+		}
+	}
+	return nil
+}
+
+// NewSubtitle builds a subtitle of the given format ("srt" or "vtt") from
+// cues, which must pass ValidateCues
+func NewSubtitle(format string, cues []*Cue) *Subtitle {
+	s := &Subtitle{Format: format, newline: "\n"}
+	if format == "vtt" {
+		s.blocks = append(s.blocks, subtitleBlock{raw: "WEBVTT"})
+	}
+	for i, cue := range cues {
+		if format == "srt" && cue.Index == "" {
+			cue.Index = strconv.Itoa(i + 1)
+		}
+		s.blocks = append(s.blocks, subtitleBlock{cue: cue})
+	}
+	return s
+}
+
+// Cues returns the cues of the subtitle, in order
+func (s *Subtitle) Cues() []*Cue {
+	var cues []*Cue
+	for _, block := range s.blocks {
+		if block.cue != nil {
+			cues = append(cues, block.cue)
+		}
+	}
+	return cues
+}
+
+// Bytes renders the subtitle in its original format and line endings
+func (s *Subtitle) Bytes() []byte {
+	var buf bytes.Buffer
+	for i, block := range s.blocks {
+		if i > 0 {
+			buf.WriteString("\n\n")
+		}
+		if block.cue == nil {
+			buf.WriteString(block.raw)
+			continue
+		}
+		if block.cue.Index != "" {
+			buf.WriteString(block.cue.Index + "\n")
+		}
+		buf.WriteString(block.cue.Start + " --> " + block.cue.End + block.cue.Settings + "\n")
+		buf.WriteString(block.cue.Text)
+	}
+	buf.WriteString("\n")
+	return bytes.ReplaceAll(buf.Bytes(), []byte("\n"), []byte(s.newline))
+}
+
+// translateSubtitle translates the text of every cue, keeping indices and
+// timestamps untouched
+func translateSubtitle(data []byte, fn TranslateFunc) ([]byte, error) {
+	s, err := ParseSubtitle(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := TranslateCues(s.Cues(), fn, DefaultLineLength); err != nil {
+		return nil, err
+	}
+	return s.Bytes(), nil
+}
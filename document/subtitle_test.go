@@ -0,0 +1,66 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 15:40:03
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 15:40:03
+ * @FilePath: /DeepLX/document/subtitle_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSubtitle(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		cues   int
+	}{
+		{"srt", "1\n00:00:01,000 --> 00:00:02,500\nHello.\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld.\n", "srt", 2},
+		{"srt with CRLF and BOM", "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\nHello.\r\n", "srt", 1},
+		{"vtt with header blocks", "WEBVTT\n\nNOTE a note\n\n00:01.000 --> 00:02.000 align:start\nHello.\n", "vtt", 1},
+	}
+	for _, tt := range tests {
+		s, err := ParseSubtitle([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%s: ParseSubtitle() error = %v", tt.name, err)
+			continue
+		}
+		if s.Format != tt.format || len(s.Cues()) != tt.cues {
+			t.Errorf("%s: format %s with %d cues, want %s with %d", tt.name, s.Format, len(s.Cues()), tt.format, tt.cues)
+		}
+		if got := strings.TrimPrefix(tt.input, "\ufeff"); string(s.Bytes()) != got {
+			t.Errorf("%s: Bytes() = %q, want %q", tt.name, s.Bytes(), got)
+		}
+	}
+}
+
+func TestParseSubtitleErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", " \n\n ", ErrEmptySubtitle.Error()},
+		{"invalid timestamp", "1\n00:00:01 --> 00:00:02,000\nHello.\n", "invalid timing line"},
+		{"no cue", "Just some text\n\nand more text\n", "no cue found"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSubtitle([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ParseSubtitle() error = %v, want %q", tt.name, err, tt.want)
+		}
+		if empty := errors.Is(err, ErrEmptySubtitle); empty != (tt.name == "empty") {
+			t.Errorf("%s: errors.Is(err, ErrEmptySubtitle) = %v", tt.name, empty)
+		}
+	}
+}
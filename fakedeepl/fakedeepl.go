@@ -0,0 +1,369 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 14:12:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 14:12:40
+ * @FilePath: /DeepLX/fakedeepl/fakedeepl.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+// Package fakedeepl is a fake of the DeepL JSON-RPC endpoint, so DeepLX can
+// be run and tested offline
+package fakedeepl
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Texts with a special meaning. A request holding one of them is answered
+// with a failure instead of a translation.
+const (
+	// TextRateLimited is answered with 429 Too Many Requests
+	TextRateLimited = "__429__"
+	// TextMalformed is answered with a body that is not valid JSON
+	TextMalformed = "__malformed__"
+	// TextEmpty is answered with a result without texts
+	TextEmpty = "__empty__"
+)
+
+// Request is a request received by the Server
+type Request struct {
+	Header     http.Header
+	Method     string
+	SourceLang string
+	TargetLang string
+	Texts      []string
+	Body       []byte
+}
+
+// Server answers LMT_handle_texts requests like DeepL does. The translation
+// of a text is the text prefixed with its target language, like
+// "[DE] Hello", and every requested alternative is numbered, like
+// "[DE] Hello (1)". The source language is detected when it is "auto".
+// LMT_handle_jobs requests of DeepL Write are answered alike, with the
+// regional variant as target language and a numbered beam per alternative.
+type Server struct {
+	mu           sync.Mutex
+	encoding     string
+	hideEncoding bool
+	rateLimited  int
+	failure      map[string]any
+	requests     []Request
+}
+
+// New returns a Server answering with uncompressed bodies
+func New() *Server {
+	return &Server{}
+}
+
+// SetEncoding compresses the following responses with "gzip", "br",
+// "deflate" or "zstd", or not at all when encoding is empty. A comma
+// separated list like "gzip, br" stacks encodings in the order listed.
+func (s *Server) SetEncoding(encoding string) {
+	s.mu.Lock()
+	s.encoding = encoding
+	s.mu.Unlock()
+}
+
+// HideEncoding omits the Content-Encoding header of compressed responses,
+// like relays that drop it but keep the compression
+func (s *Server) HideEncoding(hide bool) {
+	s.mu.Lock()
+	s.hideEncoding = hide
+	s.mu.Unlock()
+}
+
+// RateLimit answers the next n requests with 429 Too Many Requests
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	s.rateLimited = n
+	s.mu.Unlock()
+}
+
+// SetError answers the following requests with a JSON-RPC error object of
+// code, message and data, which is omitted when nil. A zero code answers
+// with translations again.
+func (s *Server) SetError(code int64, message string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if code == 0 {
+		s.failure = nil
+		return
+	}
+	s.failure = rpcError(0, code, message)
+	if data != nil {
+		s.failure["error"].(map[string]any)["data"] = data
+	}
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the received requests and restores the defaults
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.encoding = ""
+	s.hideEncoding = false
+	s.rateLimited = 0
+	s.failure = nil
+	s.requests = nil
+	s.mu.Unlock()
+}
+
+// rpcRequest is the part of a JSON-RPC request read by the Server
+type rpcRequest struct {
+	Method string `json:"method"`
+	ID     int64  `json:"id"`
+	Params struct {
+		Lang struct {
+			SourceLangUserSelected string `json:"source_lang_user_selected"`
+			TargetLang             string `json:"target_lang"`
+		} `json:"lang"`
+		Texts []struct {
+			Text                string `json:"text"`
+			RequestAlternatives int    `json:"requestAlternatives"`
+		} `json:"texts"`
+		CommonJobParams struct {
+			RegionalVariant string `json:"regionalVariant"`
+		} `json:"commonJobParams"`
+		Jobs []struct {
+			PreferredNumBeams int `json:"preferred_num_beams"`
+			Sentences         []struct {
+				Text string `json:"text"`
+			} `json:"sentences"`
+		} `json:"jobs"`
+	} `json:"params"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rpc rpcRequest
+	if err := json.Unmarshal(body, &rpc); err != nil {
+		writeJSON(w, responseEncoding{}, http.StatusBadRequest, rpcError(0, -32700, "Parse error"))
+		return
+	}
+
+	received := Request{
+		Header:     r.Header.Clone(),
+		Method:     rpc.Method,
+		SourceLang: rpc.Params.Lang.SourceLangUserSelected,
+		TargetLang: rpc.Params.Lang.TargetLang,
+		Body:       body,
+	}
+	for _, text := range rpc.Params.Texts {
+		received.Texts = append(received.Texts, text.Text)
+	}
+	for _, job := range rpc.Params.Jobs {
+		for _, sentence := range job.Sentences {
+			received.Texts = append(received.Texts, sentence.Text)
+		}
+	}
+	special := ""
+	for _, text := range received.Texts {
+		switch text {
+		case TextRateLimited, TextMalformed, TextEmpty:
+			special = text
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, received)
+	encoding := responseEncoding{s.encoding, s.hideEncoding}
+	failure := s.failure
+	if s.rateLimited > 0 {
+		s.rateLimited--
+		special = TextRateLimited
+	}
+	s.mu.Unlock()
+
+	switch {
+	case special == TextRateLimited:
+		writeJSON(w, encoding, http.StatusTooManyRequests, rpcError(rpc.ID, 1042912, "Too many requests"))
+		return
+	case failure != nil:
+		response := map[string]any{}
+		for key, value := range failure {
+			response[key] = value
+		}
+		response["id"] = rpc.ID
+		writeJSON(w, encoding, http.StatusOK, response)
+		return
+	case special == TextMalformed:
+		write(w, encoding, http.StatusOK, []byte(`{"jsonrpc":"2.0","result":{"texts":[`))
+		return
+	case rpc.Method != "LMT_handle_texts" && rpc.Method != "LMT_handle_jobs":
+		writeJSON(w, encoding, http.StatusOK, rpcError(rpc.ID, -32601, "Method not found"))
+		return
+	}
+
+	sourceLang := strings.ToUpper(received.SourceLang)
+	if sourceLang == "" || sourceLang == "AUTO" {
+		sourceLang = strings.ToUpper(whatlanggo.DetectLang(strings.Join(received.Texts, "\n")).Iso6391())
+	}
+
+	if rpc.Method == "LMT_handle_jobs" {
+		targetLang := received.TargetLang
+		if variant := rpc.Params.CommonJobParams.RegionalVariant; variant != "" {
+			targetLang = strings.ToUpper(variant)
+		}
+		translations := []map[string]any{}
+		for _, job := range rpc.Params.Jobs {
+			beams := []map[string]any{}
+			for i := 0; i < max(job.PreferredNumBeams, 1); i++ {
+				sentences := []map[string]any{}
+				for _, sentence := range job.Sentences {
+					text := fmt.Sprintf("[%s] %s", targetLang, sentence.Text)
+					if i > 0 {
+						text = fmt.Sprintf("%s (%d)", text, i)
+					}
+					sentences = append(sentences, map[string]any{"text": text})
+				}
+				beams = append(beams, map[string]any{"sentences": sentences})
+			}
+			translations = append(translations, map[string]any{"beams": beams})
+		}
+		writeJSON(w, encoding, http.StatusOK, map[string]any{
+			"jsonrpc": "2.0",
+			"id":      rpc.ID,
+			"result": map[string]any{
+				"translations": translations,
+				"source_lang":  sourceLang,
+				"target_lang":  received.TargetLang,
+			},
+		})
+		return
+	}
+
+	texts := []map[string]any{}
+	if special != TextEmpty {
+		for _, text := range rpc.Params.Texts {
+			translation := fmt.Sprintf("[%s] %s", received.TargetLang, text.Text)
+			alternatives := []map[string]any{}
+			for i := 1; i <= text.RequestAlternatives; i++ {
+				alternatives = append(alternatives, map[string]any{
+					"text": fmt.Sprintf("%s (%d)", translation, i),
+				})
+			}
+			texts = append(texts, map[string]any{
+				"text":         translation,
+				"alternatives": alternatives,
+			})
+		}
+	}
+
+	writeJSON(w, encoding, http.StatusOK, map[string]any{
+		"jsonrpc": "2.0",
+		"id":      rpc.ID,
+		"result": map[string]any{
+			"lang":              sourceLang,
+			"lang_is_confident": true,
+			"detectedLanguages": map[string]any{},
+			"texts":             texts,
+		},
+	})
+}
+
+// rpcError returns a JSON-RPC error response
+func rpcError(id int64, code int64, message string) map[string]any {
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
+
+// responseEncoding is how a response body is compressed
+type responseEncoding struct {
+	names  string
+	hidden bool
+}
+
+func writeJSON(w http.ResponseWriter, encoding responseEncoding, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	write(w, encoding, status, body)
+}
+
+// write writes body compressed with encoding
+func write(w http.ResponseWriter, encoding responseEncoding, status int, body []byte) {
+	if encoding.names != "" {
+		for _, name := range strings.Split(encoding.names, ",") {
+			compressed, err := compress(body, strings.TrimSpace(name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			body = compressed
+		}
+		if !encoding.hidden {
+			w.Header().Set("Content-Encoding", encoding.names)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// compress compresses body with a single content encoding
+func compress(body []byte, encoding string) ([]byte, error) {
+	var out bytes.Buffer
+	var compressor io.WriteCloser
+	switch encoding {
+	case "gzip":
+		compressor = gzip.NewWriter(&out)
+	case "br":
+		compressor = brotli.NewWriter(&out)
+	case "deflate":
+		compressor, _ = flate.NewWriter(&out, flate.DefaultCompression)
+	case "zstd":
+		encoder, err := zstd.NewWriter(&out)
+		if err != nil {
+			return nil, err
+		}
+		compressor = encoder
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	if _, err := compressor.Write(body); err != nil {
+		return nil, err
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
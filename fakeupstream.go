@@ -0,0 +1,42 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 14:36:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 14:36:52
+ * @FilePath: /DeepLX/fakeupstream.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/OwO-Network/DeepLX/fakedeepl"
+)
+
+// fakeUpstream implements "deeplx fake-upstream", serving a fake of the
+// DeepL JSON-RPC endpoint to point upstream_url at
+func fakeUpstream(args []string) error {
+	fs := flag.NewFlagSet("fake-upstream", flag.ExitOnError)
+	ip := fs.String("ip", "127.0.0.1", "set up the IP address to bind to")
+	port := fs.Int("port", 1189, "set up the port to listen on")
+	encoding := fs.String("encoding", "", "compress responses with 'gzip', 'br', 'deflate' or 'zstd', comma separated to stack them")
+	hideEncoding := fs.Bool("hide-encoding", false, "omit the Content-Encoding header of compressed responses")
+	fs.Parse(args)
+
+	server := fakedeepl.New()
+	server.SetEncoding(*encoding)
+	server.HideEncoding(*hideEncoding)
+
+	address := net.JoinHostPort(*ip, strconv.Itoa(*port))
+	fmt.Printf("Fake DeepL JSON-RPC endpoint listening on http://%s/jsonrpc\n", address)
+	return http.ListenAndServe(address, server)
+}
@@ -0,0 +1,75 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 21:40:18
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:18:40
+ * @FilePath: /DeepLX/localize.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/service"
+)
+
+// localize implements "deeplx localize", translating a localization file
+// from the command line
+func localize(args []string) error {
+	fs, cfg := newCommand("localize", "-t LANG [options] FILE")
+	targetLang := fs.String("t", "", "set the target language")
+	sourceLang := fs.String("source", "", "set the source language, detected when empty")
+	format := fs.String("format", "", "set the file format, taken from the file extension when empty")
+	existingPath := fs.String("existing", "", "set a previous translation of the file whose entries are kept")
+	overwrite := fs.Bool("overwrite", false, "translate entries that are already translated again")
+	output := fs.String("o", "", "set the output file, stdout when empty")
+	fs.Parse(args)
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *targetLang == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	service.ApplyConfig(cfg)
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = document.FormatOf(path)
+	}
+	if !document.LocalizationSupported(*format) {
+		return fmt.Errorf("unsupported localization format: %s", *format)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var existing []byte
+	if *existingPath != "" {
+		if existing, err = os.ReadFile(*existingPath); err != nil {
+			return err
+		}
+	}
+
+	out, _, err := document.TranslateLocalization(*format, data, existing, *overwrite, service.LocalizationTranslator(textsTranslator(cfg, *sourceLang, *targetLang), func(warning string) {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}))
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(*output, out, 0o644)
+}
@@ -13,22 +13,89 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/OwO-Network/DeepLX/service"
 )
 
+// commands maps the subcommands to their implementation. Without a
+// subcommand the server is started, as before subcommands existed.
+var commands = map[string]func(args []string) error{
+	"serve":         serve,
+	"translate":     translateCommand,
+	"localize":      localize,
+	"fake-upstream": fakeUpstream,
+}
+
 func main() {
-	cfg := service.InitConfig()
+	args := os.Args[1:]
+	command := serve
+	if len(args) > 0 {
+		if fn, ok := commands[args[0]]; ok {
+			command = fn
+			args = args[1:]
+		}
+	}
+
+	if err := command(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
-	fmt.Printf("DeepL X has been successfully launched! Listening on %v:%v\n", cfg.IP, cfg.Port)
-	fmt.Println("Developed by sjlleo <i@leo.moe> and missuo <me@missuo.me>.")
+// serve implements "deeplx serve", starting the HTTP server
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	cfg := service.NewConfig(fs)
+	fs.Parse(args)
+	err := cfg.Load()
+	if cfg.CheckConfig {
+		if err != nil {
+			return err
+		}
+		fmt.Println("Configuration is valid.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.PrintConfig {
+		fmt.Print(cfg.Redacted())
+		return nil
+	}
 
 	// Setting the application to release mode
 	gin.SetMode(gin.ReleaseMode)
 
-	app := service.Router(cfg)
-	app.Run(fmt.Sprintf("%v:%v", cfg.IP, cfg.Port))
+	// Shut down gracefully on SIGINT and SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Reload the configuration on SIGHUP or config file changes
+	live := service.NewLiveConfig(cfg)
+	go live.Watch(ctx)
+
+	// From here on errors are logged in the configured format
+	server, err := service.NewServer(live)
+	if err != nil {
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("DeepL X has been successfully launched!", "address", fmt.Sprintf("%v:%v", cfg.IP, cfg.Port))
+	slog.Info("Developed by sjlleo <i@leo.moe> and missuo <me@missuo.me>.")
+
+	if err := server.Run(ctx); err != nil {
+		slog.Error("Server stopped", "error", err)
+		os.Exit(1)
+	}
+	return nil
 }
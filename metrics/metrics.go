@@ -0,0 +1,136 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 01:05:12
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 01:05:12
+ * @FilePath: /DeepLX/metrics/metrics.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+// Package metrics holds the Prometheus metrics of DeepLX
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "deeplx_http_requests_total",
+		Help: "HTTP requests served, by endpoint, method and status.",
+	}, []string{"endpoint", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "deeplx_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests, by endpoint, method and status.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"endpoint", "method", "status"})
+
+	inFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "deeplx_http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	upstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "deeplx_upstream_requests_total",
+		Help: "Requests sent to DeepL, by proxy, dl-session and status. Status is \"error\" when no response was received.",
+	}, []string{"proxy", "session", "status"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "deeplx_upstream_request_duration_seconds",
+		Help:    "Latency of requests sent to DeepL, by proxy and dl-session.",
+		Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"proxy", "session"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "deeplx_upstream_rate_limited_total",
+		Help: "Requests DeepL rate limited, by proxy and dl-session.",
+	}, []string{"proxy", "session"})
+
+	characters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "deeplx_characters_translated_total",
+		Help: "Characters translated by DeepL, by source and target language.",
+	}, []string{"source_lang", "target_lang"})
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration, inFlight, upstreamRequests, upstreamDuration, rateLimited, characters)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RequestStarted counts a request as in flight until the returned function
+// records its outcome
+func RequestStarted() func(endpoint, method string, status int) {
+	start := time.Now()
+	inFlight.Inc()
+	return func(endpoint, method string, status int) {
+		inFlight.Dec()
+		code := strconv.Itoa(status)
+		requests.WithLabelValues(endpoint, method, code).Inc()
+		requestDuration.WithLabelValues(endpoint, method, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// UpstreamRequest records a request sent to DeepL through proxyURL with
+// dlSession. A status of 0 means no response was received.
+func UpstreamRequest(proxyURL, dlSession string, status int, duration time.Duration) {
+	proxy, session := proxyLabel(proxyURL), sessionLabel(dlSession)
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	upstreamRequests.WithLabelValues(proxy, session, code).Inc()
+	upstreamDuration.WithLabelValues(proxy, session).Observe(duration.Seconds())
+	if status == http.StatusTooManyRequests {
+		rateLimited.WithLabelValues(proxy, session).Inc()
+	}
+}
+
+// RateLimited counts a request DeepL rate limited with a JSON-RPC error
+// instead of 429 Too Many Requests
+func RateLimited(proxyURL, dlSession string) {
+	rateLimited.WithLabelValues(proxyLabel(proxyURL), sessionLabel(dlSession)).Inc()
+}
+
+// CharactersTranslated records count characters translated from sourceLang
+// to targetLang
+func CharactersTranslated(sourceLang, targetLang string, count int) {
+	characters.WithLabelValues(sourceLang, targetLang).Add(float64(count))
+}
+
+// proxyLabel returns the proxy URL without its password, or "direct"
+func proxyLabel(proxyURL string) string {
+	if proxyURL == "" {
+		return "direct"
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return "invalid"
+	}
+	return u.Redacted()
+}
+
+// sessionLabel identifies a dl-session by a short hash, so the session
+// itself never shows up in the metrics, or returns "none"
+func sessionLabel(dlSession string) string {
+	if dlSession == "" {
+		return "none"
+	}
+	sum := sha256.Sum256([]byte(dlSession))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,205 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 19:34:20
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 11:25:50
+ * @FilePath: /DeepLX/service/backend.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// DefaultBackend is the backend of DeepL's web endpoint, always available
+const DefaultBackend = "deeplx"
+
+// Backend types of BackendConfig
+const (
+	BackendDeepLX         = "deeplx"
+	BackendDeepL          = "deepl"
+	BackendLibreTranslate = "libretranslate"
+	BackendOpenAI         = "openai"
+	BackendFallback       = "fallback"
+)
+
+// BackendConfig configures a translation backend
+type BackendConfig struct {
+	// Type is "deepl" for the official DeepL API, "libretranslate",
+	// "openai" for OpenAI compatible chat completions, "deeplx" or
+	// "fallback" for a chain of the other backends
+	Type string `yaml:"type" toml:"type"`
+	// URL of the API, the official endpoint of deepl and openai when empty
+	URL string `yaml:"url" toml:"url"`
+	// APIKey is the auth key of deepl, or the API key of the others
+	APIKey string `yaml:"api_key" toml:"api_key"`
+	// Model is the chat model of openai
+	Model string `yaml:"model" toml:"model"`
+	// Steps are the backends tried in turn by fallback
+	Steps []FallbackStep `yaml:"steps,omitempty" toml:"steps,omitempty"`
+}
+
+// tokenKey is the context key of the access token a request was
+// authorized with
+const tokenKey = "deeplx.token"
+
+// backendName returns the name of the backend serving route for a request
+// authorized with token. The backend of the token takes precedence over
+// the one of the route, and both over the default backend.
+func (cfg *Config) backendName(route, token string) string {
+	name := cfg.Backend
+	if backend, ok := cfg.RouteBackends[route]; ok {
+		name = backend
+	}
+	if backend, ok := cfg.KeyBackends[token]; ok && token != "" {
+		name = backend
+	}
+	if name == "" {
+		name = DefaultBackend
+	}
+	return name
+}
+
+// translator returns the backend of the given name, DeepL's web endpoint
+// when it is not configured
+func (cfg *Config) translator(name string) translate.Translator {
+	backend, ok := cfg.Backends[name]
+	if !ok {
+		return translate.NewDeepLX()
+	}
+	switch backend.Type {
+	case BackendDeepL:
+		return translate.NewDeepLAPI(name, backend.URL, backend.APIKey)
+	case BackendLibreTranslate:
+		return translate.NewLibreTranslate(name, backend.URL, backend.APIKey)
+	case BackendOpenAI:
+		return translate.NewOpenAI(name, backend.URL, backend.APIKey, backend.Model)
+	case BackendFallback:
+		return &fallbackTranslator{cfg: cfg, name: name, steps: backend.Steps}
+	}
+	return translate.NewNamedDeepLX(name)
+}
+
+// DefaultTranslator returns the backend of routes and tokens without one of
+// their own
+func (cfg *Config) DefaultTranslator() translate.Translator {
+	return cfg.translator(cfg.backendName("", ""))
+}
+
+// requestTranslator returns the backend serving the route of a request
+func requestTranslator(c *gin.Context, cfg *Config) translate.Translator {
+	return cfg.translator(cfg.backendName(c.FullPath(), c.GetString(tokenKey)))
+}
+
+// validateBackends checks the backends and the references to them
+func (cfg *Config) validateBackends() []error {
+	var errs []error
+	for _, name := range sortedKeys(cfg.Backends) {
+		backend := cfg.Backends[name]
+		if name == DefaultBackend {
+			errs = append(errs, fmt.Errorf("backend name %q is reserved", name))
+			continue
+		}
+		switch backend.Type {
+		case BackendDeepLX:
+		case BackendDeepL:
+			if backend.APIKey == "" {
+				errs = append(errs, fmt.Errorf("backend %q needs an api_key", name))
+			}
+		case BackendLibreTranslate:
+			if backend.URL == "" {
+				errs = append(errs, fmt.Errorf("backend %q needs a url", name))
+			}
+		case BackendOpenAI:
+			if backend.Model == "" {
+				errs = append(errs, fmt.Errorf("backend %q needs a model", name))
+			}
+			if backend.URL == "" && backend.APIKey == "" {
+				errs = append(errs, fmt.Errorf("backend %q needs an api_key", name))
+			}
+		case BackendFallback:
+			errs = append(errs, cfg.validateSteps(name, backend.Steps)...)
+		default:
+			errs = append(errs, fmt.Errorf("backend %q has unknown type %q, expected 'deepl', 'libretranslate', 'openai', 'deeplx' or 'fallback'", name, backend.Type))
+		}
+		if backend.Type != BackendFallback && len(backend.Steps) > 0 {
+			errs = append(errs, fmt.Errorf("backend %q has steps, which only fallback backends have", name))
+		}
+		if backend.URL != "" {
+			if u, err := url.Parse(backend.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("backend %q has an invalid url %q", name, redactURL(backend.URL)))
+			}
+		}
+	}
+
+	known := func(name string) bool {
+		_, ok := cfg.Backends[name]
+		return name == DefaultBackend || ok
+	}
+	if cfg.Backend != "" && !known(cfg.Backend) {
+		errs = append(errs, fmt.Errorf("unknown backend %q", cfg.Backend))
+	}
+	for _, route := range sortedKeys(cfg.RouteBackends) {
+		if !strings.HasPrefix(route, "/") {
+			errs = append(errs, fmt.Errorf("route_backends route %q must start with /", route))
+		}
+		if name := cfg.RouteBackends[route]; !known(name) {
+			errs = append(errs, fmt.Errorf("route_backends uses unknown backend %q for %s", name, route))
+		}
+	}
+	tokens := cfg.tokens()
+	for _, token := range sortedKeys(cfg.KeyBackends) {
+		if !slices.Contains(tokens, token) {
+			errs = append(errs, fmt.Errorf("key_backends has the key with fingerprint %s, which is not an accepted token", fingerprint(token)))
+		}
+		if name := cfg.KeyBackends[token]; !known(name) {
+			errs = append(errs, fmt.Errorf("key_backends uses unknown backend %q for the key with fingerprint %s", name, fingerprint(token)))
+		}
+	}
+	return errs
+}
+
+// redactBackends returns the backends and key_backends of cfg with their
+// keys redacted. The access tokens of key_backends are replaced by their
+// fingerprint, as logged for the caller of a request.
+func (cfg *Config) redactBackends() (map[string]BackendConfig, map[string]string) {
+	var backends map[string]BackendConfig
+	if cfg.Backends != nil {
+		backends = make(map[string]BackendConfig, len(cfg.Backends))
+		for name, backend := range cfg.Backends {
+			backend.APIKey = redactSecret(backend.APIKey)
+			backend.URL = redactURL(backend.URL)
+			backends[name] = backend
+		}
+	}
+	var keyBackends map[string]string
+	if cfg.KeyBackends != nil {
+		keyBackends = make(map[string]string, len(cfg.KeyBackends))
+		for token, name := range cfg.KeyBackends {
+			keyBackends[fingerprint(token)] = name
+		}
+	}
+	return backends, keyBackends
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
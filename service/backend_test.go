@@ -0,0 +1,300 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 20:12:09
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 20:12:09
+ * @FilePath: /DeepLX/service/backend_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// backendRequest is a request received by a fake backend
+type backendRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// fakeBackend is a fake translation API answering with respond
+type fakeBackend struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []backendRequest
+}
+
+func newFakeBackend(t *testing.T, respond func(body map[string]any) (int, any)) *fakeBackend {
+	t.Helper()
+	backend := &fakeBackend{}
+	backend.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		backend.mu.Lock()
+		backend.requests = append(backend.requests, backendRequest{r.URL.Path, r.Header.Clone(), body})
+		backend.mu.Unlock()
+
+		status, response := respond(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// last returns the last request received
+func (b *fakeBackend) last(t *testing.T) backendRequest {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.requests) == 0 {
+		t.Fatal("backend got no request")
+	}
+	return b.requests[len(b.requests)-1]
+}
+
+// newDeepLAPIBackend fakes the official DeepL API
+func newDeepLAPIBackend(t *testing.T) *fakeBackend {
+	return newFakeBackend(t, func(body map[string]any) (int, any) {
+		texts, _ := body["text"].([]any)
+		var translations []map[string]string
+		for _, text := range texts {
+			if text == "quota" {
+				return translate.StatusQuotaExceeded, map[string]string{"message": "Quota exceeded"}
+			}
+			translations = append(translations, map[string]string{
+				"detected_source_language": "EN",
+				"text":                     fmt.Sprintf("official/%v %v", body["target_lang"], text),
+			})
+		}
+		return http.StatusOK, map[string]any{"translations": translations}
+	})
+}
+
+// newLibreBackend fakes LibreTranslate
+func newLibreBackend(t *testing.T) *fakeBackend {
+	return newFakeBackend(t, func(body map[string]any) (int, any) {
+		if body["q"] == "slow down" {
+			return http.StatusTooManyRequests, map[string]string{"error": "Slowdown: 1 per 1 second"}
+		}
+		return http.StatusOK, map[string]any{
+			"translatedText":   fmt.Sprintf("libre/%v %v", body["target"], body["q"]),
+			"detectedLanguage": map[string]any{"language": "en", "confidence": 90},
+			"alternatives":     []string{"alternative"},
+		}
+	})
+}
+
+// newOpenAIBackend fakes an OpenAI compatible chat completions API
+func newOpenAIBackend(t *testing.T) *fakeBackend {
+	return newFakeBackend(t, func(body map[string]any) (int, any) {
+		messages, _ := body["messages"].([]any)
+		text := ""
+		if len(messages) == 2 {
+			text, _ = messages[1].(map[string]any)["content"].(string)
+		}
+		if text == "quota" {
+			return http.StatusTooManyRequests, map[string]any{"error": map[string]string{
+				"message": "You exceeded your current quota, please check your plan and billing details.",
+				"code":    "insufficient_quota",
+			}}
+		}
+		return http.StatusOK, map[string]any{"choices": []any{
+			map[string]any{"message": map[string]string{"role": "assistant", "content": " gpt: " + text + "\n"}},
+		}}
+	})
+}
+
+// writeConfig writes a config file for newTestRouter
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBackendSelection(t *testing.T) {
+	official := newDeepLAPIBackend(t)
+	libre := newLibreBackend(t)
+	gpt := newOpenAIBackend(t)
+
+	config := writeConfig(t, fmt.Sprintf(`
+token: t1
+api_keys: [k2, k3]
+backends:
+  official:
+    type: deepl
+    url: %s
+    api_key: auth-key
+  libre:
+    type: libretranslate
+    url: %s
+  gpt:
+    type: openai
+    url: %s
+    api_key: sk-test
+    model: test-model
+route_backends:
+  /v2/translate: official
+  /translate/subtitle: libre
+key_backends:
+  k3: gpt
+`, official.URL, libre.URL, gpt.URL))
+	router, upstream := newTestRouter(t, "-config", config)
+
+	tests := []struct {
+		name  string
+		token string
+		req   *http.Request
+		want  string
+	}{
+		{"default backend", "t1", postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`), "[DE] Hello"},
+		{"route backend", "t1", postJSON("/v2/translate", `{"text":["Hello"],"target_lang":"DE"}`), "official/DE Hello"},
+		{"key backend", "k3", postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`), "gpt: Hello"},
+		{"key backend over route backend", "k3", postJSON("/v2/translate", `{"text":["Hello"],"target_lang":"DE"}`), "gpt: Hello"},
+		{"route backend of other key", "k2", postJSON("/v2/translate", `{"text":["Hello"],"target_lang":"DE"}`), "official/DE Hello"},
+		{"batch route without batch backend", "t1", postJSON("/translate/subtitle", `{"text":"1\n00:00:01,000 --> 00:00:02,000\nHello.\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld.\n","target_lang":"DE"}`), "libre/de World."},
+	}
+	for _, tt := range tests {
+		tt.req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, tt.req)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, body %s", tt.name, w.Code, w.Body)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: body %s, want it to contain %q", tt.name, w.Body, tt.want)
+		}
+	}
+
+	if n := len(upstream.Requests()); n != 1 {
+		t.Errorf("DeepL web endpoint got %d requests, want 1", n)
+	}
+
+	got := official.last(t)
+	if got.path != "/v2/translate" || got.header.Get("Authorization") != "DeepL-Auth-Key auth-key" {
+		t.Errorf("official API request = %s with Authorization %q", got.path, got.header.Get("Authorization"))
+	}
+	got = gpt.last(t)
+	if got.path != "/chat/completions" || got.header.Get("Authorization") != "Bearer sk-test" || got.body["model"] != "test-model" {
+		t.Errorf("chat completions request = %s with Authorization %q and model %v", got.path, got.header.Get("Authorization"), got.body["model"])
+	}
+	got = libre.last(t)
+	if got.path != "/translate" || got.body["q"] != "World." || got.body["source"] != "en" || got.body["target"] != "de" {
+		t.Errorf("LibreTranslate request = %s with body %v", got.path, got.body)
+	}
+}
+
+func TestNamedDeepLXBackend(t *testing.T) {
+	config := writeConfig(t, `
+backends:
+  web: {type: deeplx}
+backend: web
+`)
+	router, _ := newTestRouter(t, "-config", config)
+
+	_, body := serve(t, router, postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`))
+	if body["data"] != "[DE] Hello" || body["method"] != "web" {
+		t.Errorf("/translate = %v, want the backend name as method", body)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, postJSON("/translate/stream", `{"text":"One.\n\nTwo.","source_lang":"EN","target_lang":"DE"}`))
+	events := parseEvents(t, w.Body.String())
+	var summary streamSummary
+	if err := json.Unmarshal([]byte(events[len(events)-1].data), &summary); err != nil || summary.Method != "web" {
+		t.Errorf("stream summary = %+v, %v, want the backend name as method", summary, err)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	official := newDeepLAPIBackend(t)
+	libre := newLibreBackend(t)
+	gpt := newOpenAIBackend(t)
+
+	tests := []struct {
+		name    string
+		backend string
+		text    string
+		status  int
+		message string
+	}{
+		{"official quota", fmt.Sprintf("{type: deepl, url: %s, api_key: key}", official.URL), "quota", translate.StatusQuotaExceeded, "official error 456: Quota exceeded"},
+		{"libre rate limited", fmt.Sprintf("{type: libretranslate, url: %s}", libre.URL), "slow down", http.StatusTooManyRequests, "Slowdown"},
+		{"openai quota", fmt.Sprintf("{type: openai, url: %s, model: m}", gpt.URL), "quota", translate.StatusQuotaExceeded, "exceeded your current quota"},
+		{"unreachable", "{type: libretranslate, url: 'http://127.0.0.1:1'}", "Hello", http.StatusServiceUnavailable, "connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := writeConfig(t, "backend: official\nbackends:\n  official: "+tt.backend+"\n")
+			router, _ := newTestRouter(t, "-config", config)
+
+			payload, _ := json.Marshal(map[string]string{"text": tt.text, "source_lang": "EN", "target_lang": "DE"})
+			w, body := serve(t, router, postJSON("/translate", string(payload)))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if message, _ := body["message"].(string); !strings.Contains(message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", message, tt.message)
+			}
+		})
+	}
+}
+
+func TestBackendConfig(t *testing.T) {
+	config := writeConfig(t, `
+token: t1
+backends:
+  deeplx: {type: deepl, api_key: key}
+  official: {type: deepl}
+  gpt: {type: openai, url: "ftp://example.com"}
+  other: {type: bogus}
+backend: missing
+route_backends:
+  v2/translate: official
+key_backends:
+  unknown: official
+`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	fs.Parse([]string{"-config", config})
+	err := cfg.Load()
+	if err == nil {
+		t.Fatal("Load() succeeded, want errors")
+	}
+	for _, want := range []string{
+		`backend name "deeplx" is reserved`,
+		`backend "official" needs an api_key`,
+		`backend "gpt" needs a model`,
+		`backend "gpt" has an invalid url "ftp://example.com"`,
+		`backend "other" has unknown type "bogus"`,
+		`unknown backend "missing"`,
+		`route_backends route "v2/translate" must start with /`,
+		"key_backends has the key with fingerprint " + fingerprint("unknown"),
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -2,7 +2,7 @@
  * @Author: Vincent Yang
  * @Date: 2024-04-23 00:39:03
  * @LastEditors: Jason Lyu
- * @LastEditTime: 2025-04-08 13:45:00
+ * @LastEditTime: 2026-10-20 00:02:36
  * @FilePath: /DeepLX/config.go
  * @Telegram: https://t.me/missuo
  * @GitHub: https://github.com/missuo
@@ -13,63 +13,622 @@
 package service
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/translate"
 )
 
+// Config holds every setting of DeepLX. Settings are read from, in order of
+// increasing precedence:
+//
+//  1. the built-in defaults
+//  2. the config file given by -config or CONFIG_FILE, in YAML or TOML
+//  3. the environment variables listed in envVars
+//  4. the command line flags
+//
+// List settings given by environment variable or flag are comma separated
+// and replace the list of the config file.
 type Config struct {
-	IP        string
-	Port      int
-	Token     string
-	DlSession string
-	Proxy     string
+	IP         string   `yaml:"ip" toml:"ip"`
+	Port       int      `yaml:"port" toml:"port"`
+	Token      string   `yaml:"token" toml:"token"`
+	APIKeys    []string `yaml:"api_keys" toml:"api_keys"`
+	DlSession  string   `yaml:"dl_session" toml:"dl_session"`
+	DlSessions []string `yaml:"dl_sessions" toml:"dl_sessions"`
+	Proxy      string   `yaml:"proxy" toml:"proxy"`
+	Proxies    []string `yaml:"proxies" toml:"proxies"`
+
+	// UpstreamURL, UserAgent and UpstreamHeaders configure the JSON-RPC
+	// endpoint, like a local stub or an internal relay
+	UpstreamURL     string            `yaml:"upstream_url" toml:"upstream_url"`
+	UserAgent       string            `yaml:"user_agent" toml:"user_agent"`
+	UpstreamHeaders map[string]string `yaml:"upstream_headers" toml:"upstream_headers"`
+
+	// Backend is the translation backend of routes and access tokens
+	// without one of their own, DefaultBackend when empty. RouteBackends
+	// selects the backend by route, like "/v2/translate", and KeyBackends by
+	// access token, the latter taking precedence.
+	Backend       string                   `yaml:"backend" toml:"backend"`
+	Backends      map[string]BackendConfig `yaml:"backends" toml:"backends"`
+	RouteBackends map[string]string        `yaml:"route_backends" toml:"route_backends"`
+	KeyBackends   map[string]string        `yaml:"key_backends" toml:"key_backends"`
+
+	// Passthrough forwards /v2/* requests to the official DeepL API with the
+	// key of PassthroughKeys with the most characters left, checked every
+	// PassthroughUsageInterval. PassthroughURL is picked from each key when
+	// empty.
+	Passthrough              bool     `yaml:"passthrough" toml:"passthrough"`
+	PassthroughKeys          []string `yaml:"passthrough_keys" toml:"passthrough_keys"`
+	PassthroughURL           string   `yaml:"passthrough_url" toml:"passthrough_url"`
+	PassthroughUsageInterval Duration `yaml:"passthrough_usage_interval" toml:"passthrough_usage_interval"`
+
+	// DetectConfidence is the confidence below which the detected source
+	// language is left to DeepL
+	DetectConfidence float64 `yaml:"detect_confidence" toml:"detect_confidence"`
+
+	StreamConcurrency int `yaml:"stream_concurrency" toml:"stream_concurrency"`
+	MaxTextLength     int `yaml:"max_text_length" toml:"max_text_length"`
+	ChunkConcurrency  int `yaml:"chunk_concurrency" toml:"chunk_concurrency"`
+
+	DocumentDir     string `yaml:"document_dir" toml:"document_dir"`
+	DocumentWorkers int    `yaml:"document_workers" toml:"document_workers"`
+	// DocumentTTL is how long finished document jobs are kept after their
+	// last change, 0 keeps them until their result is downloaded
+	DocumentTTL Duration `yaml:"document_ttl" toml:"document_ttl"`
+
+	SubtitleLineLength int `yaml:"subtitle_line_length" toml:"subtitle_line_length"`
+
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	// Cooldown is how long a rate limited proxy or dl-session is avoided
+	Cooldown Duration `yaml:"cooldown" toml:"cooldown"`
+	// ReadyDeepCheck makes /readyz always translate a short text upstream,
+	// caching the outcome for DeepCheckInterval
+	ReadyDeepCheck    bool     `yaml:"ready_deep_check" toml:"ready_deep_check"`
+	DeepCheckInterval Duration `yaml:"deep_check_interval" toml:"deep_check_interval"`
+
+	LogLevel  string `yaml:"log_level" toml:"log_level"`
+	LogFormat string `yaml:"log_format" toml:"log_format"`
+	// LogText logs the texts and translations of requests
+	LogText bool `yaml:"log_text" toml:"log_text"`
+
+	// TraceExporter is "otlp", "stdout" or empty to disable tracing
+	TraceExporter    string  `yaml:"trace_exporter" toml:"trace_exporter"`
+	TraceEndpoint    string  `yaml:"trace_endpoint" toml:"trace_endpoint"`
+	TraceSampleRatio float64 `yaml:"trace_sample_ratio" toml:"trace_sample_ratio"`
+
+	// WatchConfig reloads the configuration when the config file changes
+	WatchConfig bool `yaml:"watch_config" toml:"watch_config"`
+
+	// ConfigFile is the config file the settings were read from
+	ConfigFile  string `yaml:"-" toml:"-"`
+	PrintConfig bool   `yaml:"-" toml:"-"`
+	CheckConfig bool   `yaml:"-" toml:"-"`
+
+	flags     *flag.FlagSet
+	cmdline   []flagValue
+	proxy     uint64
+	dlSession uint64
+}
+
+// flagValue is a flag given on the command line
+type flagValue struct {
+	name, value string
+}
+
+// envVars maps the flags that can also be set by environment variable to
+// that variable
+var envVars = map[string]string{
+	"ip":               "IP",
+	"port":             "PORT",
+	"token":            "TOKEN",
+	"api-keys":         "API_KEYS",
+	"s":                "DL_SESSION",
+	"dl-sessions":      "DL_SESSIONS",
+	"proxy":            "PROXY",
+	"proxies":          "PROXIES",
+	"upstream-url":     "UPSTREAM_URL",
+	"backend":          "BACKEND",
+	"passthrough-keys": "PASSTHROUGH_KEYS",
+	"log-level":        "LOG_LEVEL",
+	"log-format":       "LOG_FORMAT",
+}
+
+// listValue is a flag.Value of a comma separated list. Setting it replaces
+// the list instead of appending to it, so the flag overrides the config file.
+type listValue struct {
+	list *[]string
+}
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v listValue) Set(value string) error {
+	*v.list = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v.list = append(*v.list, item)
+		}
+	}
+	return nil
+}
+
+// Duration is a time.Duration written like "30s" in flags and config files
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
 }
 
-func InitConfig() *Config {
-	cfg := &Config{
+// defaultConfig returns the built-in defaults
+func defaultConfig() Config {
+	return Config{
 		IP:   "0.0.0.0",
 		Port: 1188,
+
+		DetectConfidence: translate.DefaultDetectConfidence,
+
+		StreamConcurrency: 3,
+		MaxTextLength:     translate.DefaultMaxTextLength,
+		ChunkConcurrency:  translate.DefaultChunkConcurrency,
+
+		DocumentDir:     filepath.Join(os.TempDir(), "deeplx", "documents"),
+		DocumentWorkers: 1,
+		DocumentTTL:     Duration(24 * time.Hour),
+
+		SubtitleLineLength: document.DefaultLineLength,
+
+		ReadTimeout:     Duration(30 * time.Second),
+		IdleTimeout:     Duration(2 * time.Minute),
+		ShutdownTimeout: Duration(30 * time.Second),
+
+		LogLevel:  "info",
+		LogFormat: "text",
+
+		TraceSampleRatio: 1,
+
+		PassthroughUsageInterval: Duration(5 * time.Minute),
+
+		Cooldown:          Duration(translate.DefaultCooldown),
+		DeepCheckInterval: Duration(time.Minute),
 	}
+}
+
+// InitConfig parses the configuration from the command line flags, the
+// environment and the config file
+func InitConfig() (*Config, error) {
+	cfg := NewConfig(flag.CommandLine)
+	flag.Parse()
+	return cfg, cfg.Load()
+}
+
+// NewConfig returns the default configuration and defines its flags on fs.
+// Once fs is parsed, Load applies the config file, the environment and the
+// flags, which lets subcommands share the flags with the server.
+func NewConfig(fs *flag.FlagSet) *Config {
+	cfg := defaultConfig()
+	cfg.flags = fs
+
+	// Config file flags
+	fs.StringVar(&cfg.ConfigFile, "config", "", "set the YAML or TOML config file, defaults to $CONFIG_FILE")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	fs.BoolVar(&cfg.CheckConfig, "check-config", false, "validate the configuration and exit")
+	fs.BoolVar(&cfg.WatchConfig, "watch-config", false, "reload the configuration when the config file changes")
 
 	// IP flag
-	if ip, ok := os.LookupEnv("IP"); ok && ip != "" {
-		cfg.IP = ip
-	}
-	flag.StringVar(&cfg.IP, "ip", cfg.IP, "set up the IP address to bind to")
-	flag.StringVar(&cfg.IP, "i", cfg.IP, "set up the IP address to bind to")
+	fs.StringVar(&cfg.IP, "ip", cfg.IP, "set up the IP address to bind to")
+	fs.StringVar(&cfg.IP, "i", cfg.IP, "set up the IP address to bind to")
 
 	// Port flag
-	if port, ok := os.LookupEnv("PORT"); ok && port != "" {
-		fmt.Sscanf(port, "%d", &cfg.Port)
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "set up the port to listen on")
+	fs.IntVar(&cfg.Port, "p", cfg.Port, "set up the port to listen on")
+
+	// DL Session flags
+	fs.StringVar(&cfg.DlSession, "s", "", "set the dl-session for /v1/translate endpoint")
+	fs.Var(listValue{&cfg.DlSessions}, "dl-sessions", "set a comma separated list of dl-sessions used in turn by /v1/translate")
+
+	// Access token flags
+	fs.StringVar(&cfg.Token, "token", "", "set the access token for /translate endpoint")
+	fs.Var(listValue{&cfg.APIKeys}, "api-keys", "set a comma separated list of further accepted access tokens")
+
+	// HTTP Proxy flags
+	fs.StringVar(&cfg.Proxy, "proxy", "", "set the proxy URL for HTTP requests")
+	fs.Var(listValue{&cfg.Proxies}, "proxies", "set a comma separated list of proxy URLs used in turn for HTTP requests")
+
+	// Upstream flags, headers are set in the config file
+	fs.StringVar(&cfg.UpstreamURL, "upstream-url", cfg.UpstreamURL, "set the JSON-RPC endpoint URL, DeepL when empty")
+	fs.StringVar(&cfg.UserAgent, "user-agent", cfg.UserAgent, "set the User-Agent of upstream requests")
+
+	// Backend flag, backends are configured in the config file
+	fs.StringVar(&cfg.Backend, "backend", cfg.Backend, "set the translation backend of routes and tokens without one of their own")
+
+	// Official API passthrough flags
+	fs.BoolVar(&cfg.Passthrough, "passthrough", cfg.Passthrough, "forward /v2/* requests to the official DeepL API")
+	fs.Var(listValue{&cfg.PassthroughKeys}, "passthrough-keys", "set a comma separated list of DeepL API auth keys used by -passthrough")
+	fs.StringVar(&cfg.PassthroughURL, "passthrough-url", cfg.PassthroughURL, "set the DeepL API URL of -passthrough, picked from each key when empty")
+	fs.Var(&cfg.PassthroughUsageInterval, "passthrough-usage-interval", "set how often the usage of the passthrough keys is checked")
+
+	// Language detection flag
+	fs.Float64Var(&cfg.DetectConfidence, "detect-confidence", cfg.DetectConfidence, "set the confidence below which the detected source language is left to DeepL")
+
+	// Stream concurrency flag
+	fs.IntVar(&cfg.StreamConcurrency, "stream-concurrency", cfg.StreamConcurrency, "set the number of paragraphs translated in parallel by /translate/stream")
+
+	// Chunking flags
+	fs.IntVar(&cfg.MaxTextLength, "max-text-length", cfg.MaxTextLength, "set the length above which texts are split into chunks")
+	fs.IntVar(&cfg.ChunkConcurrency, "chunk-concurrency", cfg.ChunkConcurrency, "set the number of chunks translated in parallel")
+
+	// Document translation flags
+	fs.StringVar(&cfg.DocumentDir, "document-dir", cfg.DocumentDir, "set the directory where document translation jobs are stored")
+	fs.IntVar(&cfg.DocumentWorkers, "document-workers", cfg.DocumentWorkers, "set the number of documents translated in parallel")
+	fs.Var(&cfg.DocumentTTL, "document-ttl", "set how long finished document jobs are kept, 0 keeps them until downloaded")
+
+	// Subtitle flag
+	fs.IntVar(&cfg.SubtitleLineLength, "subtitle-line-length", cfg.SubtitleLineLength, "set the maximum line length of translated subtitles, 0 disables wrapping")
+
+	// HTTP server flags
+	fs.Var(&cfg.ReadTimeout, "read-timeout", "set the maximum duration for reading a request, 0 disables it")
+	fs.Var(&cfg.WriteTimeout, "write-timeout", "set the maximum duration for writing a response, 0 disables it")
+	fs.Var(&cfg.IdleTimeout, "idle-timeout", "set how long idle keep-alive connections are kept open")
+	fs.Var(&cfg.ShutdownTimeout, "shutdown-timeout", "set how long in-flight requests are waited for on shutdown")
+
+	// Logging flags
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "set the log level, 'debug', 'info', 'warn' or 'error'")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "set the log format, 'text' or 'json'")
+	fs.BoolVar(&cfg.LogText, "log-text", cfg.LogText, "log the texts and translations of requests")
+
+	// Tracing flags
+	fs.StringVar(&cfg.TraceExporter, "trace-exporter", cfg.TraceExporter, "set the trace exporter, 'otlp' or 'stdout', tracing is disabled when empty")
+	fs.StringVar(&cfg.TraceEndpoint, "trace-endpoint", cfg.TraceEndpoint, "set the OTLP/HTTP endpoint URL, defaults to $OTEL_EXPORTER_OTLP_ENDPOINT")
+	fs.Float64Var(&cfg.TraceSampleRatio, "trace-sample-ratio", cfg.TraceSampleRatio, "set the ratio of new traces that are sampled")
+
+	// Cooldown flag
+	fs.Var(&cfg.Cooldown, "cooldown", "set how long a proxy or dl-session rate limited by DeepL is avoided")
+
+	// Readiness flags
+	fs.BoolVar(&cfg.ReadyDeepCheck, "ready-deep-check", cfg.ReadyDeepCheck, "make /readyz translate a short text upstream")
+	fs.Var(&cfg.DeepCheckInterval, "deep-check-interval", "set how long the outcome of the /readyz deep check is cached")
+
+	return &cfg
+}
+
+// Load applies the config file, the environment variables and the flags set
+// on the command line to the defaults, in that order, and validates the
+// result. It must be called after the flag set of NewConfig was parsed.
+func (cfg *Config) Load() error {
+	// The flags write to the fields of cfg, so remember the values given on
+	// the command line before resetting them to the defaults
+	if cfg.cmdline == nil {
+		cfg.cmdline = []flagValue{}
+		cfg.flags.Visit(func(f *flag.Flag) {
+			cfg.cmdline = append(cfg.cmdline, flagValue{f.Name, f.Value.String()})
+		})
+	}
+
+	fs, cmdline := cfg.flags, cfg.cmdline
+	*cfg = defaultConfig()
+	cfg.flags, cfg.cmdline = fs, cmdline
+
+	path := os.Getenv("CONFIG_FILE")
+	for _, f := range cmdline {
+		if f.name == "config" {
+			path = f.value
+		}
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return err
+		}
 	}
-	flag.IntVar(&cfg.Port, "port", cfg.Port, "set up the port to listen on")
-	flag.IntVar(&cfg.Port, "p", cfg.Port, "set up the port to listen on")
 
-	// DL Session flag
-	flag.StringVar(&cfg.DlSession, "s", "", "set the dl-session for /v1/translate endpoint")
-	if cfg.DlSession == "" {
-		if dlSession, ok := os.LookupEnv("DL_SESSION"); ok {
-			cfg.DlSession = dlSession
+	for name, env := range envVars {
+		if value, ok := os.LookupEnv(env); ok && value != "" {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
 		}
 	}
 
-	// Access token flag
-	flag.StringVar(&cfg.Token, "token", "", "set the access token for /translate endpoint")
-	if cfg.Token == "" {
-		if token, ok := os.LookupEnv("TOKEN"); ok {
-			cfg.Token = token
+	for _, f := range cmdline {
+		// Flags of subcommands are not known to the flag set of a reload
+		if fs.Lookup(f.name) == nil {
+			continue
+		}
+		if err := fs.Set(f.name, f.value); err != nil {
+			return err
 		}
 	}
+	cfg.ConfigFile = path
+
+	return cfg.Validate()
+}
+
+// Reload loads the configuration again from the same config file,
+// environment and command line into a new Config, leaving cfg unchanged
+func (cfg *Config) Reload() (*Config, error) {
+	next := NewConfig(flag.NewFlagSet(cfg.flags.Name(), flag.ContinueOnError))
+	next.cmdline = cfg.cmdline
+	if err := next.Load(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
 
-	// HTTP Proxy flag
-	flag.StringVar(&cfg.Proxy, "proxy", "", "set the proxy URL for HTTP requests")
-	if cfg.Proxy == "" {
-		if proxy, ok := os.LookupEnv("PROXY"); ok {
-			cfg.Proxy = proxy
+// loadFile reads the config file at path, as TOML for a .toml extension and
+// as YAML otherwise. Unknown keys are an error.
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(cfg); errors.Is(err, io.EOF) {
+			err = nil
 		}
 	}
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
 
-	flag.Parse()
-	return cfg
+// Validate reports every invalid setting of cfg
+func (cfg *Config) Validate() error {
+	var errs []error
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", cfg.Port))
+	}
+	if cfg.UpstreamURL != "" {
+		if u, err := url.Parse(cfg.UpstreamURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid upstream_url %q", redactURL(cfg.UpstreamURL)))
+		}
+	}
+	if cfg.Passthrough && len(cfg.PassthroughKeys) == 0 {
+		errs = append(errs, errors.New("passthrough needs passthrough_keys"))
+	}
+	if cfg.PassthroughURL != "" {
+		if u, err := url.Parse(cfg.PassthroughURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid passthrough_url %q", redactURL(cfg.PassthroughURL)))
+		}
+	}
+	for _, proxy := range cfg.proxies() {
+		if u, err := url.Parse(proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid proxy URL %q", redactURL(proxy)))
+		}
+	}
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"stream_concurrency", cfg.StreamConcurrency},
+		{"max_text_length", cfg.MaxTextLength},
+		{"chunk_concurrency", cfg.ChunkConcurrency},
+		{"document_workers", cfg.DocumentWorkers},
+	} {
+		if setting.value < 1 {
+			errs = append(errs, fmt.Errorf("%s must be at least 1, got %d", setting.name, setting.value))
+		}
+	}
+	if cfg.SubtitleLineLength < 0 {
+		errs = append(errs, fmt.Errorf("subtitle_line_length must not be negative, got %d", cfg.SubtitleLineLength))
+	}
+	for _, setting := range []struct {
+		name  string
+		value Duration
+	}{
+		{"read_timeout", cfg.ReadTimeout},
+		{"write_timeout", cfg.WriteTimeout},
+		{"idle_timeout", cfg.IdleTimeout},
+		{"shutdown_timeout", cfg.ShutdownTimeout},
+		{"document_ttl", cfg.DocumentTTL},
+		{"cooldown", cfg.Cooldown},
+		{"deep_check_interval", cfg.DeepCheckInterval},
+	} {
+		if setting.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", setting.name, setting.value))
+		}
+	}
+	if cfg.PassthroughUsageInterval <= 0 {
+		errs = append(errs, fmt.Errorf("passthrough_usage_interval must be positive, got %s", cfg.PassthroughUsageInterval))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("invalid log_level %q", cfg.LogLevel))
+	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log_format must be 'text' or 'json', got %q", cfg.LogFormat))
+	}
+	switch cfg.TraceExporter {
+	case "", "none", "otlp", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("trace_exporter must be 'otlp', 'stdout' or empty, got %q", cfg.TraceExporter))
+	}
+	if cfg.DetectConfidence < 0 || cfg.DetectConfidence > 1 {
+		errs = append(errs, fmt.Errorf("detect_confidence must be between 0 and 1, got %v", cfg.DetectConfidence))
+	}
+	if cfg.TraceSampleRatio < 0 || cfg.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("trace_sample_ratio must be between 0 and 1, got %v", cfg.TraceSampleRatio))
+	}
+	if cfg.DocumentDir == "" {
+		errs = append(errs, errors.New("document_dir must not be empty"))
+	}
+	errs = append(errs, cfg.validateBackends()...)
+	return errors.Join(errs...)
+}
+
+// Redacted returns the effective configuration as YAML with access tokens,
+// dl-sessions and proxy passwords redacted
+func (cfg *Config) Redacted() string {
+	redacted := *cfg
+	redacted.Token = redactSecret(cfg.Token)
+	redacted.DlSession = redactSecret(cfg.DlSession)
+	redacted.Proxy = redactURL(cfg.Proxy)
+	redacted.APIKeys = redactList(cfg.APIKeys, redactSecret)
+	redacted.DlSessions = redactList(cfg.DlSessions, redactSecret)
+	redacted.Proxies = redactList(cfg.Proxies, redactURL)
+	redacted.UpstreamURL = redactURL(cfg.UpstreamURL)
+	redacted.PassthroughKeys = redactList(cfg.PassthroughKeys, redactSecret)
+	redacted.PassthroughURL = redactURL(cfg.PassthroughURL)
+	if cfg.UpstreamHeaders != nil {
+		redacted.UpstreamHeaders = make(map[string]string, len(cfg.UpstreamHeaders))
+		for name, value := range cfg.UpstreamHeaders {
+			if secretHeader.MatchString(name) {
+				value = redactSecret(value)
+			}
+			redacted.UpstreamHeaders[name] = value
+		}
+	}
+	redacted.Backends, redacted.KeyBackends = cfg.redactBackends()
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return err.Error()
+	}
+	return out.String()
+}
+
+// secretHeader matches the names of headers whose values are redacted
+var secretHeader = regexp.MustCompile(`(?i)authorization|cookie|token|key|secret`)
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+func redactList(list []string, redact func(string) string) []string {
+	if list == nil {
+		return nil
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		out[i] = redact(item)
+	}
+	return out
+}
+
+// Upstream returns the JSON-RPC endpoint settings of the translate package
+func (cfg *Config) Upstream() translate.Upstream {
+	return translate.Upstream{
+		URL:       cfg.UpstreamURL,
+		UserAgent: cfg.UserAgent,
+		Headers:   cfg.UpstreamHeaders,
+	}
+}
+
+// passthroughURL returns the official DeepL API URL key is sent to
+func (cfg *Config) passthroughURL(key string) string {
+	if cfg.PassthroughURL != "" {
+		return cfg.PassthroughURL
+	}
+	return translate.OfficialAPIURL(key)
+}
+
+// proxies returns every configured proxy URL
+func (cfg *Config) proxies() []string {
+	return appendNonEmpty(cfg.Proxy, cfg.Proxies)
+}
+
+// dlSessions returns every configured dl-session
+func (cfg *Config) dlSessions() []string {
+	return appendNonEmpty(cfg.DlSession, cfg.DlSessions)
+}
+
+// tokens returns every accepted access token
+func (cfg *Config) tokens() []string {
+	return appendNonEmpty(cfg.Token, cfg.APIKeys)
+}
+
+func appendNonEmpty(value string, list []string) []string {
+	if value == "" {
+		return list
+	}
+	return append([]string{value}, list...)
+}
+
+// NextProxy returns the proxy URL for the next upstream request, rotating
+// through the configured proxies and skipping those in cooldown unless all
+// are. It is empty when no proxy is configured.
+func (cfg *Config) NextProxy() string {
+	return next(cfg.proxies(), &cfg.proxy, translate.ProxyInCooldown)
+}
+
+// NextDlSession returns the dl-session for the next Pro request, rotating
+// through the configured dl-sessions and skipping those in cooldown unless
+// all are. It is empty when none is configured.
+func (cfg *Config) NextDlSession() string {
+	return next(cfg.dlSessions(), &cfg.dlSession, translate.SessionInCooldown)
+}
+
+// next returns the next item of list in turn that is not in cooldown, or
+// simply the next one when all of them are
+func next(list []string, counter *uint64, inCooldown func(string) bool) string {
+	if len(list) == 0 {
+		return ""
+	}
+	start := atomic.AddUint64(counter, 1) - 1
+	for i := uint64(0); i < uint64(len(list)); i++ {
+		if item := list[(start+i)%uint64(len(list))]; !inCooldown(item) {
+			return item
+		}
+	}
+	return list[start%uint64(len(list))]
 }
@@ -0,0 +1,156 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 20:31:16
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 20:31:05
+ * @FilePath: /DeepLX/service/detect.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+const (
+	// defaultDetectCandidates is the number of candidates of a detection
+	defaultDetectCandidates = 3
+	// maxDetectCandidates caps the candidates a request may ask for
+	maxDetectCandidates = 10
+	// maxConfirmTexts caps the texts of a request with confirm, each costing
+	// an upstream request
+	maxConfirmTexts = 10
+	// maxConfirmLength is how many characters of a text are sent upstream to
+	// confirm its language, more do not help DeepL detect it
+	maxConfirmLength = 1000
+)
+
+// textList is a single text or a list of texts in JSON
+type textList []string
+
+func (l *textList) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*l = textList{text}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+type PayloadDetect struct {
+	Text            textList `json:"text"`
+	SourceLangHints []string `json:"source_lang_hints"`
+	// Candidates is the number of candidates per text, 3 when missing
+	Candidates *int `json:"candidates"`
+	// Confirm lets DeepL detect the language of every text as well, at the
+	// cost of an upstream request per text. It takes up to maxConfirmTexts
+	// texts, of which the first maxConfirmLength characters are sent.
+	Confirm bool `json:"confirm"`
+}
+
+// detectResult is the detection of a text returned by /detect
+type detectResult struct {
+	translate.Detection
+	Candidates []translate.Detection `json:"candidates"`
+	// ConfirmedLang is the language detected by DeepL with confirm
+	ConfirmedLang string `json:"confirmed_lang,omitempty"`
+}
+
+// detectHandler detects the language of one or many texts locally, without
+// an upstream request unless confirm is set
+func detectHandler(live *LiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		req := PayloadDetect{}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid request payload",
+			})
+			return
+		}
+		if strings.TrimSpace(strings.Join(req.Text, "")) == "" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "No text to detect",
+			})
+			return
+		}
+
+		if req.Confirm && len(req.Text) > maxConfirmTexts {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("At most %d texts can be confirmed at once", maxConfirmTexts),
+			})
+			return
+		}
+
+		candidates := defaultDetectCandidates
+		if req.Candidates != nil {
+			candidates = min(max(*req.Candidates, 0), maxDetectCandidates)
+		}
+
+		var proxyURL string
+		if req.Confirm {
+			proxyURL = cfg.NextProxy()
+		}
+		start := time.Now()
+		results := make([]detectResult, len(req.Text))
+		for i, text := range req.Text {
+			results[i] = detectResult{
+				Detection:  translate.DetectLanguage(text, req.SourceLangHints),
+				Candidates: translate.DetectCandidates(text, req.SourceLangHints, candidates),
+			}
+			if results[i].Candidates == nil {
+				results[i].Candidates = []translate.Detection{}
+			}
+			if !req.Confirm || strings.TrimSpace(text) == "" {
+				continue
+			}
+			if runes := []rune(text); len(runes) > maxConfirmLength {
+				text = string(runes[:maxConfirmLength])
+			}
+			lang, err := translate.ConfirmLanguage(c.Request.Context(), text, proxyURL, "")
+			if err != nil {
+				status := translate.ErrorStatus(err)
+				logTranslation(c, cfg, "", "", strings.Join(req.Text, "\n"), translate.DeepLXTranslationResult{
+					Code:    status,
+					Message: err.Error(),
+				}, proxyURL, time.Since(start))
+				c.JSON(status, gin.H{
+					"code":    status,
+					"message": err.Error(),
+				})
+				return
+			}
+			results[i].ConfirmedLang = lang
+		}
+
+		// The language pair of the log is the detected language of the first text
+		detected := results[0].SourceLang
+		if results[0].ConfirmedLang != "" {
+			detected = results[0].ConfirmedLang
+		}
+		logTranslation(c, cfg, "", "", strings.Join(req.Text, "\n"), translate.DeepLXTranslationResult{
+			Code:       http.StatusOK,
+			SourceLang: detected,
+		}, proxyURL, time.Since(start))
+
+		c.JSON(http.StatusOK, gin.H{
+			"code":       http.StatusOK,
+			"detections": results,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 21:05:38
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 21:05:38
+ * @FilePath: /DeepLX/service/detect_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// detectResponse is the body of a /detect response
+type detectResponse struct {
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Detections []struct {
+		Lang          string  `json:"lang"`
+		SourceLang    string  `json:"source_lang"`
+		Confidence    float64 `json:"confidence"`
+		Script        string  `json:"script"`
+		ConfirmedLang string  `json:"confirmed_lang"`
+		Candidates    []struct {
+			Lang string `json:"lang"`
+		} `json:"candidates"`
+	} `json:"detections"`
+}
+
+func detect(t *testing.T, router http.Handler, body string) (int, detectResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, postJSON("/detect", body))
+	var response detectResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q", w.Body)
+	}
+	return w.Code, response
+}
+
+func TestDetect(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	status, response := detect(t, router, `{"text":"Hello, how are you doing today? The weather is lovely."}`)
+	if status != http.StatusOK || len(response.Detections) != 1 {
+		t.Fatalf("status = %d, response %+v", status, response)
+	}
+	got := response.Detections[0]
+	if got.Lang != "EN" || got.SourceLang != "EN" || got.Confidence != 1 || got.Script != "Latin" {
+		t.Errorf("detection = %+v, want EN in Latin script", got)
+	}
+	if len(got.Candidates) != 3 || got.Candidates[0].Lang != "EN" || got.Candidates[1].Lang == "EN" {
+		t.Errorf("candidates = %+v, want 3 starting with EN", got.Candidates)
+	}
+
+	status, response = detect(t, router, `{"text":["Мы живём в большом городе и работаем каждый день.","안녕하세요"],"candidates":1}`)
+	if status != http.StatusOK || len(response.Detections) != 2 {
+		t.Fatalf("status = %d, response %+v", status, response)
+	}
+	if got := response.Detections; got[0].Lang != "RU" || got[1].Lang != "KO" || got[1].Script != "Hangul" || len(got[0].Candidates) != 1 {
+		t.Errorf("detections = %+v, want RU and KO with one candidate", got)
+	}
+
+	if n := len(upstream.Requests()); n != 0 {
+		t.Errorf("upstream got %d requests, want 0", n)
+	}
+
+	status, response = detect(t, router, `{"text":["Hello, how are you doing today? The weather is lovely.","Das ist ein ganz normaler deutscher Satz."],"confirm":true}`)
+	if status != http.StatusOK || response.Detections[0].ConfirmedLang != "EN" || response.Detections[1].ConfirmedLang != "DE" {
+		t.Errorf("confirm: status = %d, response %+v", status, response)
+	}
+	requests := upstream.Requests()
+	if len(requests) != 2 || requests[0].SourceLang != "auto" || requests[0].TargetLang != "DE" || requests[1].TargetLang != "EN" {
+		t.Errorf("confirm: upstream requests = %+v, want one per text with the source language left to DeepL", requests)
+	}
+
+	// Confirmed texts are capped in number and length
+	many := make([]string, maxConfirmTexts+1)
+	for i := range many {
+		many[i] = "Hello"
+	}
+	texts, _ := json.Marshal(many)
+	if status, response = detect(t, router, `{"text":`+string(texts)+`,"confirm":true}`); status != http.StatusBadRequest {
+		t.Errorf("confirm with %d texts: status = %d, response %+v", maxConfirmTexts+1, status, response)
+	}
+	long := strings.Repeat("Das ist ein ganz normaler deutscher Satz. ", 50)
+	if status, response = detect(t, router, `{"text":"`+long+`","confirm":true}`); status != http.StatusOK || response.Detections[0].ConfirmedLang != "DE" {
+		t.Errorf("confirm long text: status = %d, response %+v", status, response)
+	}
+	requests = upstream.Requests()
+	if sent := requests[len(requests)-1].Texts[0]; utf8.RuneCountInString(sent) != maxConfirmLength {
+		t.Errorf("confirm long text: sent %d characters upstream, want %d", utf8.RuneCountInString(sent), maxConfirmLength)
+	}
+
+	entry := lastLogEntry(t, router, postJSON("/detect", `{"text":["Guten Morgen, wie geht es dir heute?"],"confirm":true}`))
+	if entry["source_lang"] != "DE" || entry["characters"] != 36.0 || entry["egress"] != "direct" || entry["outcome"] != "ok" {
+		t.Errorf("log entry = %v, want the detection logged", entry)
+	}
+
+	upstream.RateLimit(1)
+	if status, response = detect(t, router, `{"text":"Hello","confirm":true}`); status != http.StatusTooManyRequests {
+		t.Errorf("rate limited confirm: status = %d, response %+v", status, response)
+	}
+
+	if status, _ = detect(t, router, `{"text":[" ",""]}`); status != http.StatusNotFound {
+		t.Errorf("blank texts: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
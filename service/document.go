@@ -0,0 +1,433 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 15:31:10
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 00:02:36
+ * @FilePath: /DeepLX/service/document.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// maxDocumentSize is the largest document accepted by /v2/document
+const maxDocumentSize = 30 << 20
+
+// translationError carries a failed translation result through a document.TranslateFunc
+type translationError struct {
+	result translate.DeepLXTranslationResult
+}
+
+func (e *translationError) Error() string {
+	return e.result.Message
+}
+
+// batchOutcome is the outcome of the batches of a batchTranslator, for
+// logTranslation
+type batchOutcome struct {
+	// result is the last result, with the detected source language
+	result   translate.DeepLXTranslationResult
+	proxyURL string
+}
+
+// attrs returns the log attributes of translating text, err being the error
+// the translation ended with
+func (o *batchOutcome) attrs(cfg *Config, sourceLang, targetLang, text string, err error, latency time.Duration) []slog.Attr {
+	result := o.result
+	var te *translationError
+	switch {
+	case errors.As(err, &te):
+		result = te.result
+	case err != nil:
+		result = translate.DeepLXTranslationResult{Code: translate.ErrorStatus(err), Message: err.Error()}
+	}
+	return translationAttrs(cfg, sourceLang, targetLang, text, result, o.proxyURL, latency)
+}
+
+// log adds the outcome of translating text to the access log entry
+func (o *batchOutcome) log(c *gin.Context, cfg *Config, sourceLang, targetLang, text string, err error, latency time.Duration) {
+	annotate(c, o.attrs(cfg, sourceLang, targetLang, text, err, latency)...)
+}
+
+// recordTexts returns fn, appending the segments it is called with to texts
+func recordTexts(fn document.TranslateFunc, texts *[]string) document.TranslateFunc {
+	return func(segments []string) ([]string, error) {
+		*texts = append(*texts, segments...)
+		return fn(segments)
+	}
+}
+
+// batchTranslator returns a document.TranslateFunc translating segments with
+// t in batches. Failed translations, including the errors of t mapped with
+// translate.ErrorStatus, are returned as *translationError, and the last
+// result is stored in outcome when it is not nil.
+func batchTranslator(ctx context.Context, t translate.Translator, cfg *Config, sourceLang, targetLang string, outcome *batchOutcome) document.TranslateFunc {
+	return func(segments []string) ([]string, error) {
+		proxyURL := cfg.NextProxy()
+		result, err := translate.TranslateAll(ctx, t, translate.Request{
+			SourceLang: sourceLang,
+			TargetLang: targetLang,
+			ProxyURL:   proxyURL,
+		}, segments)
+		if outcome != nil {
+			outcome.result, outcome.proxyURL = result, proxyURL
+		}
+		if err != nil {
+			return nil, &translationError{translate.DeepLXTranslationResult{
+				Code:    translate.ErrorStatus(err),
+				Message: err.Error(),
+			}}
+		}
+		if result.Code != http.StatusOK {
+			return nil, &translationError{result}
+		}
+		return result.Texts, nil
+	}
+}
+
+// documentSweepInterval is how often expired document jobs are deleted
+const documentSweepInterval = time.Minute
+
+// documentWorker translates queued documents in the background, running at
+// most document_workers jobs at the same time, and deletes finished jobs
+// after document_ttl
+type documentWorker struct {
+	live    *LiveConfig
+	store   *document.Store
+	slots   chan struct{}
+	stopped chan struct{}
+	running sync.WaitGroup
+}
+
+func newDocumentWorker(live *LiveConfig, store *document.Store) *documentWorker {
+	workers := live.Get().DocumentWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	w := &documentWorker{
+		live:    live,
+		store:   store,
+		slots:   make(chan struct{}, workers),
+		stopped: make(chan struct{}),
+	}
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+		ticker := time.NewTicker(documentSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.sweep()
+			case <-w.stopped:
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// sweep deletes the finished jobs older than document_ttl
+func (w *documentWorker) sweep() {
+	ttl := time.Duration(w.live.Get().DocumentTTL)
+	if ttl == 0 {
+		return
+	}
+	deleted, err := w.store.Expire(time.Now().Add(-ttl))
+	if err != nil {
+		slog.Error("Failed to delete expired documents", "error", err)
+	}
+	if deleted > 0 {
+		slog.Info("Deleted expired documents", "count", deleted)
+	}
+}
+
+// enqueue schedules a job for translation
+func (w *documentWorker) enqueue(job *document.Job) {
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+		select {
+		case w.slots <- struct{}{}:
+		case <-w.stopped:
+			// Queued jobs are picked up again on the next start
+			return
+		}
+		defer func() { <-w.slots }()
+		w.run(job)
+	}()
+}
+
+// close stops starting queued jobs and the sweep, and waits for the running
+// jobs to finish
+func (w *documentWorker) close(ctx context.Context) error {
+	close(w.stopped)
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("document translations still running: %w", ctx.Err())
+	}
+}
+
+// run translates the document of a job and records the outcome
+func (w *documentWorker) run(job *document.Job) {
+	job.Status = document.StatusTranslating
+	if err := w.store.Update(job); err != nil {
+		slog.Error("Failed to update document", "document_id", job.ID, "error", err)
+	}
+
+	ctx, span := tracer.Start(context.Background(), "document.translate", trace.WithAttributes(
+		attribute.String("deeplx.document_id", job.ID),
+		attribute.String("deeplx.format", job.Format),
+	))
+	defer span.End()
+
+	cfg := w.live.Get()
+	outcome := &batchOutcome{}
+	var texts []string
+	start := time.Now()
+	err := func() error {
+		data, err := w.store.Source(job)
+		if err != nil {
+			return err
+		}
+
+		translator := cfg.translator(job.Backend)
+		out, characters, err := document.Translate(job.Format, data, recordTexts(batchTranslator(ctx, translator, cfg, job.SourceLang, job.TargetLang, outcome), &texts))
+		if err != nil {
+			return err
+		}
+
+		job.BilledCharacters = characters
+		return w.store.SaveResult(job, out)
+	}()
+
+	// The translation runs outside of a request, so it is logged on its own
+	attrs := append([]slog.Attr{slog.String("document_id", job.ID), slog.String("format", job.Format)},
+		outcome.attrs(cfg, job.SourceLang, job.TargetLang, strings.Join(texts, "\n"), err, time.Since(start))...)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelWarn, "Document translation failed", append(attrs, slog.Any("error", err))...)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		job.Status = document.StatusError
+		job.ErrorMessage = err.Error()
+	} else {
+		slog.LogAttrs(ctx, slog.LevelInfo, "Document translated", attrs...)
+		job.Status = document.StatusDone
+	}
+	if err := w.store.Update(job); err != nil {
+		slog.Error("Failed to update document", "document_id", job.ID, "error", err)
+	}
+}
+
+// documentKey returns the document_key of a status or result request, sent
+// either as form value, query parameter or JSON body
+func documentKey(c *gin.Context) string {
+	if key := c.PostForm("document_key"); key != "" {
+		return key
+	}
+	if key := c.Query("document_key"); key != "" {
+		return key
+	}
+	var body struct {
+		DocumentKey string `json:"document_key"`
+	}
+	c.ShouldBindJSON(&body)
+	return body.DocumentKey
+}
+
+// lookupDocument returns the job addressed by the request, or writes an
+// error response and returns nil
+func lookupDocument(c *gin.Context, store *document.Store) *document.Job {
+	job, err := store.Get(c.Param("id"), documentKey(c))
+	if errors.Is(err, document.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    http.StatusNotFound,
+			"message": "Document not found",
+		})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    http.StatusInternalServerError,
+			"message": err.Error(),
+		})
+		return nil
+	}
+	return job
+}
+
+// documentUploadHandler accepts a document upload and queues its translation
+// with the backend of the request
+func documentUploadHandler(worker *documentWorker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := worker.live.Get()
+		targetLang := c.PostForm("target_lang")
+		sourceLang := c.PostForm("source_lang")
+		fileHeader, err := c.FormFile("file")
+		if err != nil || targetLang == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid request payload, file and target_lang are required",
+			})
+			return
+		}
+
+		filename := fileHeader.Filename
+		if name := c.PostForm("filename"); name != "" {
+			filename = name
+		}
+		format := document.FormatOf(filename)
+		if !document.Supported(format) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("Unsupported document format '%s'", format),
+			})
+			return
+		}
+		if outputFormat := c.PostForm("output_format"); outputFormat != "" && outputFormat != format {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Conversion between document formats is not supported",
+			})
+			return
+		}
+		if fileHeader.Size > maxDocumentSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"code":    http.StatusRequestEntityTooLarge,
+				"message": "Document is too large",
+			})
+			return
+		}
+
+		f, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": err.Error(),
+			})
+			return
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		job, err := worker.store.Create(filename, sourceLang, targetLang, cfg.backendName(c.FullPath(), c.GetString(tokenKey)), data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    http.StatusInternalServerError,
+				"message": err.Error(),
+			})
+			return
+		}
+		annotate(c,
+			slog.String("document_id", job.ID),
+			slog.String("format", format),
+			slog.String("source_lang", sourceLang),
+			slog.String("target_lang", targetLang),
+		)
+		worker.enqueue(job)
+
+		c.JSON(http.StatusOK, gin.H{
+			"document_id":  job.ID,
+			"document_key": job.Key,
+		})
+	}
+}
+
+// documentStatusHandler reports the status of a document translation
+func documentStatusHandler(store *document.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job := lookupDocument(c, store)
+		if job == nil {
+			return
+		}
+
+		status := gin.H{
+			"document_id": job.ID,
+			"status":      job.Status,
+		}
+		switch job.Status {
+		case document.StatusDone:
+			status["billed_characters"] = job.BilledCharacters
+		case document.StatusError:
+			status["error_message"] = job.ErrorMessage
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// documentResultHandler returns the translated document once it is done.
+// Like the official API, the document is deleted after it was downloaded.
+func documentResultHandler(store *document.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job := lookupDocument(c, store)
+		if job == nil {
+			return
+		}
+
+		if job.Status != document.StatusDone {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    http.StatusServiceUnavailable,
+				"message": "Document is not ready",
+			})
+			return
+		}
+
+		data, err := store.Result(job)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    http.StatusInternalServerError,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		contentType := mime.TypeByExtension(filepath.Ext(job.Filename))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": job.Filename}))
+		c.Data(http.StatusOK, contentType, data)
+
+		if err := store.Delete(job); err != nil {
+			slog.Error("Failed to delete document", "document_id", job.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 19:03:48
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:41:07
+ * @FilePath: /DeepLX/service/document_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OwO-Network/DeepLX/document"
+)
+
+func TestDocumentWorkerClose(t *testing.T) {
+	store, err := document.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := NewLiveConfig(NewConfig(flag.NewFlagSet("test", flag.ContinueOnError)))
+
+	// A job waiting for a worker is left queued for the next start
+	worker := newDocumentWorker(live, store)
+	worker.slots <- struct{}{}
+	job, err := store.Create("a.txt", "EN", "DE", "", []byte("Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker.enqueue(job)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := worker.close(ctx); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	if job, err := store.Get(job.ID, job.Key); err != nil || job.Status != document.StatusQueued {
+		t.Errorf("job = %+v, %v, want it still queued", job, err)
+	}
+
+	// Running jobs are waited for until the shutdown deadline
+	worker = newDocumentWorker(live, store)
+	worker.running.Add(1)
+	defer worker.running.Done()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := worker.close(ctx); err == nil {
+		t.Error("close() succeeded with a job still running, want an error")
+	}
+}
+
+func TestDocumentWorkerSweep(t *testing.T) {
+	dir := t.TempDir()
+	store, err := document.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	if err := fs.Parse([]string{"-document-ttl", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	live := NewLiveConfig(cfg)
+	worker := newDocumentWorker(live, store)
+	defer worker.close(context.Background())
+
+	jobs := map[string]*document.Job{}
+	for _, status := range []string{document.StatusQueued, document.StatusTranslating, document.StatusDone, document.StatusError} {
+		job, err := store.Create("a.txt", "EN", "DE", "", []byte("Hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		job.Status = status
+		if err := store.Update(job); err != nil {
+			t.Fatal(err)
+		}
+		jobs[status] = job
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// A document_ttl of 0 keeps every job
+	worker.sweep()
+	for status, job := range jobs {
+		if _, err := store.Get(job.ID, job.Key); err != nil {
+			t.Errorf("%s job deleted without document_ttl: %v", status, err)
+		}
+	}
+
+	updated := *cfg
+	updated.DocumentTTL = Duration(time.Millisecond)
+	live.current.Store(&updated)
+	worker.sweep()
+	for status, job := range jobs {
+		_, err := store.Get(job.ID, job.Key)
+		expired := status == document.StatusDone || status == document.StatusError
+		if expired != errors.Is(err, document.ErrNotFound) {
+			t.Errorf("%s job: Get() error = %v, want it expired %v", status, err, expired)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 4 {
+		t.Errorf("files left = %q, want those of the 2 unfinished jobs", files)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries returns the JSON log entries written so far
+func (b *syncBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid log entry %q", line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestDocumentLogging(t *testing.T) {
+	router, _ := newTestRouter(t)
+	var out syncBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&out, nil)))
+	defer slog.SetDefault(previous)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("source_lang", "EN")
+	form.WriteField("target_lang", "DE")
+	part, _ := form.CreateFormFile("file", "a.txt")
+	part.Write([]byte("Hello."))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v2/document", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w, upload := serve(t, router, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload: status = %d, body %v", w.Code, upload)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		req := postJSON("/v2/document/"+upload["document_id"].(string), `{"document_key":"`+upload["document_key"].(string)+`"}`)
+		if _, status := serve(t, router, req); status["status"] == document.StatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("document not translated in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var uploaded, translated map[string]any
+	for _, entry := range out.entries(t) {
+		switch {
+		case entry["msg"] == "request" && entry["path"] == "/v2/document":
+			uploaded = entry
+		case entry["msg"] == "Document translated":
+			translated = entry
+		}
+	}
+	if uploaded["document_id"] != upload["document_id"] || uploaded["source_lang"] != "EN" || uploaded["target_lang"] != "DE" || uploaded["format"] != "txt" {
+		t.Errorf("upload log entry = %v", uploaded)
+	}
+	if translated["document_id"] != upload["document_id"] || translated["target_lang"] != "DE" || translated["characters"] != 6.0 || translated["outcome"] != "ok" {
+		t.Errorf("translation log entry = %v", translated)
+	}
+}
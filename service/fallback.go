@@ -0,0 +1,186 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 10:42:17
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 10:42:17
+ * @FilePath: /DeepLX/service/fallback.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// FallbackStep is a step of a fallback backend
+type FallbackStep struct {
+	// Backend is the name of the backend tried by the step
+	Backend string `yaml:"backend" toml:"backend"`
+	// Pro sends the requests of a deeplx backend with a configured
+	// dl-session, unless the request carries one already
+	Pro bool `yaml:"pro" toml:"pro"`
+	// On lists the failures of the previous step this step is tried on,
+	// defaultFallbackOn when empty. Failures are a status like "429", a
+	// class like "5xx", "timeout" or "any".
+	On []string `yaml:"on" toml:"on"`
+	// Timeout bounds the step, 0 leaves it to the request
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// defaultFallbackOn are the failures steps are tried on by default, those
+// not caused by the request itself
+var defaultFallbackOn = []string{"429", "456", "5xx"}
+
+// fallbackTranslator tries the steps of a fallback backend in turn
+type fallbackTranslator struct {
+	cfg   *Config
+	name  string
+	steps []FallbackStep
+}
+
+func (t *fallbackTranslator) Name() string {
+	return t.name
+}
+
+func (t *fallbackTranslator) Translate(ctx context.Context, req translate.Request) (translate.DeepLXTranslationResult, error) {
+	return t.run(ctx, req, func(ctx context.Context, backend translate.Translator, req translate.Request) (translate.DeepLXTranslationResult, error) {
+		return backend.Translate(ctx, req)
+	})
+}
+
+func (t *fallbackTranslator) TranslateTexts(ctx context.Context, req translate.Request, texts []string) (translate.DeepLXTranslationResult, error) {
+	return t.run(ctx, req, func(ctx context.Context, backend translate.Translator, req translate.Request) (translate.DeepLXTranslationResult, error) {
+		return translate.TranslateAll(ctx, backend, req, texts)
+	})
+}
+
+// run calls fn with the backend of every step until one succeeds, or fails
+// in a way the next step is not tried on. The result of the last step tried
+// is returned, its method reports the path that served the request.
+func (t *fallbackTranslator) run(ctx context.Context, req translate.Request, fn func(context.Context, translate.Translator, translate.Request) (translate.DeepLXTranslationResult, error)) (translate.DeepLXTranslationResult, error) {
+	var result translate.DeepLXTranslationResult
+	var err error
+	for i, step := range t.steps {
+		if i > 0 {
+			status := failureStatus(result, err)
+			if ctx.Err() != nil || !fallbackMatches(step.On, status) {
+				break
+			}
+			slog.InfoContext(ctx, "Falling back to the next backend", "backend", t.name, "step", i+1, "to", step.Backend, "status", status)
+			// The previous proxy may well be in cooldown now
+			req.ProxyURL = t.cfg.NextProxy()
+		}
+
+		stepReq := req
+		if step.Pro && stepReq.DlSession == "" {
+			stepReq.DlSession = t.cfg.NextDlSession()
+		}
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout))
+		}
+		result, err = fn(stepCtx, t.cfg.translator(step.Backend), stepReq)
+		cancel()
+		if err == nil && result.Code == http.StatusOK {
+			return result, nil
+		}
+	}
+	return result, err
+}
+
+// failureStatus returns the status of a failed step
+func failureStatus(result translate.DeepLXTranslationResult, err error) int {
+	if err != nil {
+		return translate.ErrorStatus(err)
+	}
+	return result.Code
+}
+
+// fallbackMatches reports whether a step with the conditions on is tried
+// after a failure with status
+func fallbackMatches(on []string, status int) bool {
+	if len(on) == 0 {
+		on = defaultFallbackOn
+	}
+	for _, condition := range on {
+		switch condition {
+		case "any":
+			return true
+		case "timeout":
+			if status == http.StatusGatewayTimeout {
+				return true
+			}
+		case "4xx", "5xx":
+			if strconv.Itoa(status/100) == condition[:1] {
+				return true
+			}
+		default:
+			if strconv.Itoa(status) == condition {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validFallbackCondition reports whether condition is understood by
+// fallbackMatches
+func validFallbackCondition(condition string) bool {
+	switch condition {
+	case "any", "timeout", "4xx", "5xx":
+		return true
+	}
+	status, err := strconv.Atoi(condition)
+	return err == nil && status >= 400 && status <= 599
+}
+
+// validateSteps checks the steps of the fallback backend name
+func (cfg *Config) validateSteps(name string, steps []FallbackStep) []error {
+	if len(steps) == 0 {
+		return []error{fmt.Errorf("backend %q needs steps", name)}
+	}
+	var errs []error
+	for i, step := range steps {
+		backendType := BackendDeepLX
+		if step.Backend != DefaultBackend {
+			backend, ok := cfg.Backends[step.Backend]
+			if !ok {
+				errs = append(errs, fmt.Errorf("backend %q step %d uses unknown backend %q", name, i+1, step.Backend))
+				continue
+			}
+			backendType = backend.Type
+		}
+		if backendType == BackendFallback {
+			errs = append(errs, fmt.Errorf("backend %q step %d uses the fallback backend %q", name, i+1, step.Backend))
+		}
+		if step.Pro && backendType != BackendDeepLX {
+			errs = append(errs, fmt.Errorf("backend %q step %d sets pro for the %s backend %q, only deeplx backends have dl-sessions", name, i+1, backendType, step.Backend))
+		}
+		if step.Pro && len(cfg.dlSessions()) == 0 {
+			errs = append(errs, fmt.Errorf("backend %q step %d sets pro without a configured dl-session", name, i+1))
+		}
+		if i == 0 && len(step.On) > 0 {
+			errs = append(errs, fmt.Errorf("backend %q step 1 is always tried and cannot have conditions", name))
+		}
+		for _, condition := range step.On {
+			if !validFallbackCondition(condition) {
+				errs = append(errs, fmt.Errorf("backend %q step %d has unknown condition %q, expected a status like '429', '4xx', '5xx', 'timeout' or 'any'", name, i+1, condition))
+			}
+		}
+		if step.Timeout < 0 {
+			errs = append(errs, fmt.Errorf("backend %q step %d timeout must not be negative, got %s", name, i+1, step.Timeout))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,141 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 11:20:05
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 11:20:05
+ * @FilePath: /DeepLX/service/fallback_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFallback(t *testing.T) {
+	official := newDeepLAPIBackend(t)
+	slow := newFakeBackend(t, func(body map[string]any) (int, any) {
+		time.Sleep(200 * time.Millisecond)
+		return http.StatusOK, map[string]any{"translatedText": "too late"}
+	})
+
+	config := writeConfig(t, fmt.Sprintf(`
+dl_sessions: [session]
+backend: resilient
+backends:
+  official: {type: deepl, url: %s, api_key: key}
+  slow: {type: libretranslate, url: %s}
+  resilient:
+    type: fallback
+    steps:
+      - backend: deeplx
+      - backend: deeplx
+        pro: true
+        on: [429]
+      - backend: official
+        on: [429, 5xx]
+  patient:
+    type: fallback
+    steps:
+      - backend: slow
+        timeout: 50ms
+      - backend: official
+        on: [timeout]
+route_backends:
+  /v1/translate: patient
+`, official.URL, slow.URL))
+	router, upstream := newTestRouter(t, "-config", config)
+
+	tests := []struct {
+		name        string
+		rateLimited int
+		failure     int64
+		path        string
+		status      int
+		method      string
+		upstream    int
+	}{
+		{"first step", 0, 0, "/translate", http.StatusOK, "Free", 1},
+		{"pro session on 429", 1, 0, "/translate", http.StatusOK, "Pro", 2},
+		{"official API when both are rate limited", 2, 0, "/translate", http.StatusOK, "official", 2},
+		{"no fallback on invalid requests", 0, -32600, "/translate", http.StatusBadRequest, "", 1},
+		{"official API on timeout", 0, 0, "/v1/translate", http.StatusOK, "official", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream.Reset()
+			upstream.RateLimit(tt.rateLimited)
+			upstream.SetError(tt.failure, "Invalid request", nil)
+
+			req := postJSON(tt.path, `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`)
+			req.Header.Set("Cookie", "dl_session=client")
+			w, body := serve(t, router, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if method, _ := body["method"].(string); method != tt.method {
+				t.Errorf("method = %q, want %q", method, tt.method)
+			}
+
+			requests := upstream.Requests()
+			if len(requests) != tt.upstream {
+				t.Fatalf("upstream got %d requests, want %d", len(requests), tt.upstream)
+			}
+			if tt.method == "Pro" {
+				if cookie := requests[1].Header.Get("Cookie"); cookie != "dl_session=session" {
+					t.Errorf("Pro step Cookie = %q, want the configured dl-session", cookie)
+				}
+			}
+		})
+	}
+}
+
+func TestFallbackConfig(t *testing.T) {
+	config := writeConfig(t, `
+backends:
+  official: {type: deepl, api_key: key, steps: [{backend: deeplx}]}
+  empty: {type: fallback}
+  chain:
+    type: fallback
+    steps:
+      - backend: deeplx
+        on: [429]
+      - backend: missing
+      - backend: official
+        pro: true
+      - backend: deeplx
+        pro: true
+        on: [418x, timeout]
+      - backend: empty
+`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	fs.Parse([]string{"-config", config})
+	err := cfg.Load()
+	if err == nil {
+		t.Fatal("Load() succeeded, want errors")
+	}
+	for _, want := range []string{
+		`backend "official" has steps, which only fallback backends have`,
+		`backend "empty" needs steps`,
+		`backend "chain" step 1 is always tried and cannot have conditions`,
+		`backend "chain" step 2 uses unknown backend "missing"`,
+		`backend "chain" step 3 sets pro for the deepl backend "official"`,
+		`backend "chain" step 4 sets pro without a configured dl-session`,
+		`backend "chain" step 4 has unknown condition "418x"`,
+		`backend "chain" step 5 uses the fallback backend "empty"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 00:37:25
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 00:37:25
+ * @FilePath: /DeepLX/service/health.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// component is the status of a part of DeepLX reported by /readyz
+type component struct {
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Available *int       `json:"available,omitempty"`
+	Total     *int       `json:"total,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+	statusFailing  = "failing"
+)
+
+// deepCheck translates a short text upstream, remembering the outcome so
+// that frequent probes do not hammer DeepL
+type deepCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// run returns the outcome of the last check if it is younger than
+// interval, and checks again otherwise
+func (d *deepCheck) run(ctx context.Context, cfg *Config) (time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.checkedAt.IsZero() && time.Since(d.checkedAt) < time.Duration(cfg.DeepCheckInterval) {
+		return d.checkedAt, d.err
+	}
+
+	result, err := translate.TranslateByDeepLX(ctx, "EN", nil, "DE", "Hello", "", cfg.NextProxy(), "")
+	if err == nil && result.Code != http.StatusOK {
+		err = errors.New(result.Message)
+	}
+	d.checkedAt, d.err = time.Now(), err
+	return d.checkedAt, d.err
+}
+
+// availability counts the items of list that are not in cooldown
+func availability(list []string, inCooldown func(string) bool) component {
+	available := 0
+	for _, item := range list {
+		if !inCooldown(item) {
+			available++
+		}
+	}
+	total := len(list)
+
+	c := component{Status: statusOK, Available: &available, Total: &total}
+	switch {
+	case available == 0:
+		c.Status = statusFailing
+		c.Message = "All are in cooldown after being rate limited"
+	case available < total:
+		c.Status = statusDegraded
+	}
+	return c
+}
+
+// healthzHandler reports that the process is alive
+func healthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": statusOK,
+	})
+}
+
+// readyzHandler reports whether DeepLX can serve translations. It is ready
+// when its configuration is valid and at least one egress, a proxy or
+// direct access, is not in cooldown. The dl-sessions are reported but only
+// affect /v1/translate. With ready_deep_check or ?deep=true a short text is
+// translated upstream as well, at most once per deep_check_interval.
+func readyzHandler(live *LiveConfig) gin.HandlerFunc {
+	check := &deepCheck{}
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		ready := true
+		components := map[string]component{}
+
+		if err := cfg.Validate(); err != nil {
+			components["config"] = component{Status: statusFailing, Message: err.Error()}
+			ready = false
+		} else {
+			components["config"] = component{Status: statusOK}
+		}
+
+		egress := cfg.proxies()
+		if len(egress) == 0 {
+			egress = []string{""}
+		}
+		components["egress"] = availability(egress, translate.ProxyInCooldown)
+		if components["egress"].Status == statusFailing {
+			ready = false
+		}
+
+		if sessions := cfg.dlSessions(); len(sessions) > 0 {
+			components["sessions"] = availability(sessions, translate.SessionInCooldown)
+		}
+
+		deep, _ := strconv.ParseBool(c.Query("deep"))
+		if deep || cfg.ReadyDeepCheck {
+			checkedAt, err := check.run(c.Request.Context(), cfg)
+			upstream := component{Status: statusOK, CheckedAt: &checkedAt}
+			if err != nil {
+				upstream.Status = statusFailing
+				upstream.Message = err.Error()
+				ready = false
+			}
+			components["upstream"] = upstream
+		}
+
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "not_ready", http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{
+			"status":     status,
+			"components": components,
+		})
+	}
+}
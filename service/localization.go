@@ -0,0 +1,179 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 21:14:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:41:07
+ * @FilePath: /DeepLX/service/localization.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// localizationMarkup protects inline markup and entities of localization
+// values from translation
+var localizationMarkup = translate.PlaceholderOptions{
+	Patterns: []string{`<[^>]+>`, `&[#\w]+;`},
+}
+
+// errFileTooLarge is returned for localization files over maxDocumentSize
+var errFileTooLarge = errors.New("File is too large")
+
+// LocalizationTranslator returns a document.TranslateFunc for localization
+// values, keeping their printf, ICU and Mustache placeholders and inline
+// markup intact. Placeholders lost in a translation are reported to warn
+// when it is not nil.
+func LocalizationTranslator(fn document.TranslateFunc, warn func(warning string)) document.TranslateFunc {
+	opts := localizationMarkup
+	opts.Warn = warn
+	return translate.ProtectPlaceholders(fn, opts)
+}
+
+// readLimited reads r, failing with errFileTooLarge beyond maxDocumentSize
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxDocumentSize+1))
+	if err == nil && len(data) > maxDocumentSize {
+		return nil, errFileTooLarge
+	}
+	return data, err
+}
+
+// readFormFile reads an uploaded file of a multipart request
+func readFormFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	f, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f)
+}
+
+// readError answers a request whose file could not be read
+func readError(c *gin.Context, err error, message string) {
+	status := http.StatusBadRequest
+	if errors.Is(err, errFileTooLarge) {
+		status, message = http.StatusRequestEntityTooLarge, err.Error()
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+	})
+}
+
+// localizationHandler translates the values of a localization file and
+// returns the file in the same format. The file is either uploaded as
+// multipart form with an optional "existing" translation, or posted as raw
+// body with the options in the query. Placeholders lost in the translation
+// are listed in X-Placeholder-Warnings headers.
+func localizationHandler(live *LiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		var data, existing []byte
+		format := c.Query("format")
+		if c.ContentType() == gin.MIMEMultipartPOSTForm {
+			fileHeader, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    http.StatusBadRequest,
+					"message": "Invalid request payload, file is required",
+				})
+				return
+			}
+			if data, err = readFormFile(fileHeader); err == nil {
+				if existingHeader, ferr := c.FormFile("existing"); ferr == nil {
+					existing, err = readFormFile(existingHeader)
+				}
+			}
+			if err != nil {
+				readError(c, err, err.Error())
+				return
+			}
+			if value := c.PostForm("format"); value != "" {
+				format = value
+			}
+			if format == "" {
+				format = document.FormatOf(fileHeader.Filename)
+			}
+		} else {
+			body, err := readLimited(c.Request.Body)
+			if err != nil {
+				readError(c, err, "Invalid request payload")
+				return
+			}
+			data = body
+		}
+
+		targetLang := c.DefaultPostForm("target_lang", c.Query("target_lang"))
+		sourceLang := c.DefaultPostForm("source_lang", c.Query("source_lang"))
+		if targetLang == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "target_lang is required",
+			})
+			return
+		}
+		if !document.LocalizationSupported(format) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": fmt.Sprintf("Unsupported localization format '%s'", format),
+			})
+			return
+		}
+		overwrite, _ := strconv.ParseBool(c.DefaultPostForm("overwrite", c.Query("overwrite")))
+
+		outcome := &batchOutcome{}
+		var texts, warnings []string
+		translateFunc := LocalizationTranslator(batchTranslator(c.Request.Context(), requestTranslator(c, cfg), cfg, sourceLang, targetLang, outcome),
+			func(warning string) { warnings = append(warnings, warning) })
+
+		start := time.Now()
+		out, characters, err := document.TranslateLocalization(format, data, existing, overwrite, recordTexts(translateFunc, &texts))
+		if len(texts) > 0 {
+			outcome.log(c, cfg, sourceLang, targetLang, strings.Join(texts, "\n"), err, time.Since(start))
+		}
+		if err != nil {
+			var te *translationError
+			if errors.As(err, &te) {
+				c.JSON(te.result.Code, gin.H{
+					"code":    te.result.Code,
+					"message": te.result.Message,
+				})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		contentType := mime.TypeByExtension("." + format)
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+		}
+		c.Header("X-Billed-Characters", strconv.Itoa(characters))
+		for _, warning := range warnings {
+			c.Writer.Header().Add("X-Placeholder-Warnings", warning)
+		}
+		c.Data(http.StatusOK, contentType, out)
+	}
+}
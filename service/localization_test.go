@@ -0,0 +1,81 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 23:18:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:18:40
+ * @FilePath: /DeepLX/service/localization_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// postLocalization posts a raw localization file
+func postLocalization(query, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/translate/localization?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestLocalization(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, postLocalization("format=json&source_lang=EN&target_lang=DE", `{"greeting":"Hello {name}"}`))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "[DE] Hello {name}") || len(w.Header().Values("X-Placeholder-Warnings")) != 0 {
+		t.Errorf("status = %d, body %s, warnings %q", w.Code, w.Body, w.Header().Values("X-Placeholder-Warnings"))
+	}
+
+	w, body := serve(t, router, postLocalization("format=json&target_lang=DE", strings.Repeat(" ", maxDocumentSize+1)))
+	if w.Code != http.StatusRequestEntityTooLarge || body["message"] != "File is too large" {
+		t.Errorf("too large: status = %d, body %v", w.Code, body)
+	}
+
+	w, body = serve(t, router, postLocalization("format=json&target_lang=DE", `{"greeting":`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed: status = %d, body %v", w.Code, body)
+	}
+
+	entry := lastLogEntry(t, router, postLocalization("format=json&source_lang=EN&target_lang=DE", `{"greeting":"Hello {name}","farewell":"Bye"}`))
+	if entry["source_lang"] != "EN" || entry["target_lang"] != "DE" || entry["characters"] != 16.0 || entry["outcome"] != "ok" {
+		t.Errorf("log entry = %v, want the translation logged", entry)
+	}
+
+	upstream.RateLimit(10)
+	w, body = serve(t, router, postLocalization("format=json&source_lang=EN&target_lang=DE", `{"greeting":"Hello"}`))
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("rate limited: status = %d, body %v", w.Code, body)
+	}
+}
+
+func TestLocalizationWarnings(t *testing.T) {
+	sentinel := regexp.MustCompile(`⟦\d+⟧`)
+	libre := newFakeBackend(t, func(body map[string]any) (int, any) {
+		return http.StatusOK, map[string]any{"translatedText": sentinel.ReplaceAllString(fmt.Sprint(body["q"]), "")}
+	})
+	config := writeConfig(t, fmt.Sprintf(`
+backends:
+  libre: {type: libretranslate, url: %s}
+route_backends:
+  /translate/localization: libre
+`, libre.URL))
+	router, _ := newTestRouter(t, "-config", config)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, postLocalization("format=json&source_lang=EN&target_lang=DE", `{"greeting":"Hello {name}"}`))
+	warnings := w.Header().Values("X-Placeholder-Warnings")
+	if w.Code != http.StatusOK || len(warnings) != 1 || !strings.Contains(warnings[0], `"{name}" is missing`) {
+		t.Errorf("status = %d, body %s, warnings %q", w.Code, w.Body, warnings)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 09:12:33
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:41:07
+ * @FilePath: /DeepLX/service/logging.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// requestIDHeader carries the ID of a request, taken from the client when
+// it sends one and returned in the response
+const requestIDHeader = "X-Request-ID"
+
+// logAttrsKey is the gin context key of the attributes added to the access
+// log entry of a request
+const logAttrsKey = "deeplx.log_attrs"
+
+// logLevel is shared by every logger set up by setupLogging, so a reload
+// changes the level of loggers already in use
+var logLevel slog.LevelVar
+
+// setupLogging makes a logger with the level and format of cfg the default
+// of slog and of the log package
+func setupLogging(cfg *Config) {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	logLevel.Set(level)
+
+	opts := &slog.HandlerOptions{Level: &logLevel}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// annotate adds attributes to the access log entry of the request
+func annotate(c *gin.Context, attrs ...slog.Attr) {
+	existing, _ := c.Get(logAttrsKey)
+	list, _ := existing.([]slog.Attr)
+	c.Set(logAttrsKey, append(list, attrs...))
+}
+
+// logTranslation adds the outcome of a translation to the access log entry
+// of the request, with the requested languages unless the result has them.
+// The texts themselves are only logged with log_text.
+func logTranslation(c *gin.Context, cfg *Config, sourceLang, targetLang, text string, result translate.DeepLXTranslationResult, proxyURL string, latency time.Duration) {
+	annotate(c, translationAttrs(cfg, sourceLang, targetLang, text, result, proxyURL, latency)...)
+}
+
+// translationAttrs returns the log attributes of a translation for
+// logTranslation
+func translationAttrs(cfg *Config, sourceLang, targetLang, text string, result translate.DeepLXTranslationResult, proxyURL string, latency time.Duration) []slog.Attr {
+	outcome := "ok"
+	if result.Code != http.StatusOK {
+		outcome = result.Message
+	}
+	if result.SourceLang != "" {
+		sourceLang = result.SourceLang
+	}
+	if result.TargetLang != "" {
+		targetLang = result.TargetLang
+	}
+	attrs := []slog.Attr{
+		slog.String("source_lang", sourceLang),
+		slog.String("target_lang", targetLang),
+		slog.Int("characters", utf8.RuneCountInString(text)),
+		slog.Duration("upstream_latency", latency),
+		slog.String("egress", egressLabel(proxyURL)),
+		slog.String("outcome", outcome),
+	}
+	if result.Method != "" {
+		attrs = append(attrs, slog.String("translation_method", result.Method))
+	}
+	if cfg.LogText {
+		attrs = append(attrs, slog.String("text", text), slog.String("translation", result.Data))
+	}
+	return attrs
+}
+
+// egressLabel returns the proxy URL without its password, or "direct"
+func egressLabel(proxyURL string) string {
+	if proxyURL == "" {
+		return "direct"
+	}
+	return redactURL(proxyURL)
+}
+
+// fingerprint identifies a secret like an access token by a short hash,
+// without revealing it
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// loggingMiddleware assigns every request an ID, returned in the
+// X-Request-ID header, and writes one access log entry per request with
+// the attributes added by the handlers
+func loggingMiddleware(c *gin.Context) {
+	start := time.Now()
+	requestID := c.GetHeader(requestIDHeader)
+	if requestID == "" || len(requestID) > 128 {
+		requestID = newRequestID()
+	}
+	c.Header(requestIDHeader, requestID)
+
+	c.Next()
+
+	status := c.Writer.Status()
+	attrs := []slog.Attr{
+		slog.String("request_id", requestID),
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("client_ip", c.ClientIP()),
+	}
+	if existing, ok := c.Get(logAttrsKey); ok {
+		attrs = append(attrs, existing.([]slog.Attr)...)
+	}
+	if len(c.Errors) > 0 {
+		attrs = append(attrs, slog.String("error", c.Errors.String()))
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+}
+
+// recoveryMiddleware turns a panic in a handler into a 500 response and
+// logs it with its stack
+func recoveryMiddleware(c *gin.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			slog.Error("Handler panicked", "path", c.Request.URL.Path, "error", fmt.Sprint(err), "stack", string(debug.Stack()))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":    http.StatusInternalServerError,
+				"message": "Internal server error",
+			})
+		}
+	}()
+	c.Next()
+}
+
+// translationFailed responds to an error returned by a translation
+func translationFailed(c *gin.Context, err error) {
+	c.Error(err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    http.StatusInternalServerError,
+		"message": "Translation failed",
+	})
+}
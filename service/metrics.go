@@ -0,0 +1,36 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 01:21:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 10:41:27
+ * @FilePath: /DeepLX/service/metrics.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/metrics"
+)
+
+// metricsMiddleware records the count, latency and status of every request
+// by route, and the number of requests in flight. It must run outside
+// recoveryMiddleware to see the status of panicking requests.
+func metricsMiddleware(c *gin.Context) {
+	done := metrics.RequestStarted()
+	c.Next()
+
+	endpoint := c.FullPath()
+	if endpoint == "" {
+		endpoint = c.GetString(routeKey)
+	}
+	if endpoint == "" {
+		endpoint = "unmatched"
+	}
+	done(endpoint, c.Request.Method, c.Writer.Status())
+}
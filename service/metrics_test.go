@@ -0,0 +1,53 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-23 10:41:27
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 10:41:27
+ * @FilePath: /DeepLX/service/metrics_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrape returns the metrics line starting with prefix
+func scrape(t *testing.T, router http.Handler, prefix string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestMetricsPanic(t *testing.T) {
+	router, _ := newTestRouter(t)
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	inFlight := scrape(t, router, "deeplx_http_requests_in_flight ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if got := scrape(t, router, "deeplx_http_requests_in_flight "); got != inFlight {
+		t.Errorf("in flight after a panic = %q, want %q", got, inFlight)
+	}
+	if got := scrape(t, router, `deeplx_http_requests_total{endpoint="/panic",method="GET",status="500"}`); got == "" {
+		t.Error("panicking request not counted as 500")
+	}
+}
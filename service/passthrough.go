@@ -0,0 +1,370 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 14:05:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 19:48:33
+ * @FilePath: /DeepLX/service/passthrough.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// usageTimeout bounds a /v2/usage request of the key pool
+const usageTimeout = 10 * time.Second
+
+// passthroughRoute is the metrics and tracing route of forwarded requests
+// without a route of their own
+const passthroughRoute = "/v2/*passthrough"
+
+// routeKey is the context key of the route of requests matched by NoRoute
+const routeKey = "deeplx.route"
+
+// keyUsage is the character usage of a passthrough key
+type keyUsage struct {
+	count, limit int64
+	checkedAt    time.Time
+	// usable is false when the usage check failed or the API answered that
+	// the key is invalid or its quota used up, until the next check
+	usable bool
+}
+
+// remaining returns the characters left to the key
+func (u *keyUsage) remaining() int64 {
+	return u.limit - u.count
+}
+
+// keyPool picks the passthrough key with the most characters left, checking
+// the usage of the keys with /v2/usage every passthrough_usage_interval.
+// Documents and glossaries belong to the key that created them, their
+// requests are pinned to it.
+type keyPool struct {
+	mu     sync.Mutex
+	usages map[string]*keyUsage
+	owners map[string]string
+	client *http.Client
+
+	// ctx cancels the background checks on shutdown
+	ctx        context.Context
+	stop       context.CancelFunc
+	background sync.WaitGroup
+}
+
+func newKeyPool() *keyPool {
+	ctx, stop := context.WithCancel(context.Background())
+	return &keyPool{
+		usages: map[string]*keyUsage{},
+		owners: map[string]string{},
+		client: &http.Client{Transport: http.DefaultTransport},
+		ctx:    ctx,
+		stop:   stop,
+	}
+}
+
+// close cancels the background usage checks, waits for them and closes the
+// idle connections of the usage client
+func (p *keyPool) close(ctx context.Context) error {
+	p.stop()
+	done := make(chan struct{})
+	go func() {
+		p.background.Wait()
+		close(done)
+	}()
+	defer p.client.CloseIdleConnections()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("usage checks still running: %w", ctx.Err())
+	}
+}
+
+// pick returns the usable key with the most characters left, or the one
+// with the most characters left when no key is usable. Keys never checked
+// are checked before picking, stale ones in the background so that requests
+// do not wait for /v2/usage.
+func (p *keyPool) pick(ctx context.Context, cfg *Config) string {
+	interval := time.Duration(cfg.PassthroughUsageInterval)
+
+	// Claim the keys to check, so that concurrent requests do not check them too
+	var unknown, stale []string
+	p.mu.Lock()
+	for _, key := range cfg.PassthroughKeys {
+		usage, ok := p.usages[key]
+		if !ok {
+			usage = &keyUsage{usable: true}
+			p.usages[key] = usage
+		}
+		switch {
+		case usage.checkedAt.IsZero():
+			unknown = append(unknown, key)
+		case time.Since(usage.checkedAt) >= interval:
+			stale = append(stale, key)
+		default:
+			continue
+		}
+		usage.checkedAt = time.Now()
+	}
+	p.mu.Unlock()
+
+	for _, key := range stale {
+		p.background.Add(1)
+		go func() {
+			defer p.background.Done()
+			p.check(p.ctx, cfg, key)
+		}()
+	}
+
+	// A cancelled request must not leave the key unusable
+	ctx = context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for _, key := range unknown {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.check(ctx, cfg, key)
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best, bestUsable := "", false
+	for _, key := range cfg.PassthroughKeys {
+		usage := p.usages[key]
+		switch {
+		case best == "",
+			usage.usable && !bestUsable,
+			usage.usable == bestUsable && usage.remaining() > p.usages[best].remaining():
+			best, bestUsable = key, usage.usable
+		}
+	}
+	return best
+}
+
+// check updates the usage of key from /v2/usage
+func (p *keyPool) check(ctx context.Context, cfg *Config, key string) {
+	count, limit, err := fetchUsage(ctx, p.client, cfg.passthroughURL(key), key)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to check the usage of a DeepL API key", "api_key", fingerprint(key), "error", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	usage := p.usages[key]
+	usage.count, usage.limit = count, limit
+	usage.usable = err == nil && count < limit
+}
+
+// observe marks key unusable until its next check when the API answered
+// that it is invalid or its quota used up
+func (p *keyPool) observe(key string, status int) {
+	if status != http.StatusForbidden && status != translate.StatusQuotaExceeded {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if usage, ok := p.usages[key]; ok {
+		usage.usable = false
+	}
+}
+
+// passthroughResource returns the document or glossary addressed by path,
+// like "document/<id>", or "" when the path is not about one
+func passthroughResource(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "v2" && (parts[1] == "document" || parts[1] == "glossaries") && parts[2] != "" {
+		return parts[1] + "/" + parts[2]
+	}
+	return ""
+}
+
+// createsResource reports whether r creates a document or glossary
+func createsResource(r *http.Request) bool {
+	return r.Method == http.MethodPost && (r.URL.Path == "/v2/document" || r.URL.Path == "/v2/glossaries")
+}
+
+// owner returns the key that created resource, or "" when it is unknown,
+// like after a restart, or no longer configured
+func (p *keyPool) owner(cfg *Config, resource string) string {
+	p.mu.Lock()
+	key, ok := p.owners[resource]
+	p.mu.Unlock()
+	if ok && slices.Contains(cfg.PassthroughKeys, key) {
+		return key
+	}
+	return ""
+}
+
+// track records the owner of the document or glossary created by the
+// response to r, and forgets deleted glossaries
+func (p *keyPool) track(key string, r *http.Request, resp *http.Response) error {
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil
+	}
+	if r.Method == http.MethodDelete {
+		if resource := passthroughResource(r.URL.Path); resource != "" {
+			p.mu.Lock()
+			delete(p.owners, resource)
+			p.mu.Unlock()
+		}
+		return nil
+	}
+	if !createsResource(r) {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var created struct {
+		DocumentID string `json:"document_id"`
+		GlossaryID string `json:"glossary_id"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if created.DocumentID != "" {
+		p.owners["document/"+created.DocumentID] = key
+	}
+	if created.GlossaryID != "" {
+		p.owners["glossaries/"+created.GlossaryID] = key
+	}
+	return nil
+}
+
+// fetchUsage returns the character count and limit of key
+func fetchUsage(ctx context.Context, client *http.Client, apiURL, key string) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, usageTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/v2/usage", nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+key)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("usage request failed with status code: %d", resp.StatusCode)
+	}
+
+	var usage struct {
+		CharacterCount int64 `json:"character_count"`
+		CharacterLimit int64 `json:"character_limit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return 0, 0, fmt.Errorf("invalid usage response: %w", err)
+	}
+	return usage.CharacterCount, usage.CharacterLimit, nil
+}
+
+// forward sends the request to the official DeepL API with a key of the
+// pool in place of the access token, and returns the response unchanged.
+// Requests about a document or glossary use the key that created it.
+func (p *keyPool) forward(c *gin.Context, cfg *Config) {
+	key := p.owner(cfg, passthroughResource(c.Request.URL.Path))
+	if key == "" {
+		key = p.pick(c.Request.Context(), cfg)
+	}
+	target, err := url.Parse(cfg.passthroughURL(key))
+	if err != nil {
+		translationFailed(c, err)
+		return
+	}
+	annotate(c, slog.String("api_key", fingerprint(key)))
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Header.Set("Authorization", "DeepL-Auth-Key "+key)
+			if query := r.Out.URL.Query(); query.Has("token") {
+				query.Del("token")
+				r.Out.URL.RawQuery = query.Encode()
+			}
+			// Let the transport decompress the ID of created resources
+			if createsResource(r.In) {
+				r.Out.Header.Del("Accept-Encoding")
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			p.observe(key, resp.StatusCode)
+			return p.track(key, c.Request, resp)
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			c.Error(err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"code":    http.StatusBadGateway,
+				"message": "Failed to reach the DeepL API",
+			})
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// passthroughMiddleware forwards the requests of a /v2 route to the official
+// DeepL API in passthrough mode, instead of serving them
+func passthroughMiddleware(live *LiveConfig, pool *keyPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		if !cfg.Passthrough {
+			c.Next()
+			return
+		}
+		pool.forward(c, cfg)
+		c.Abort()
+	}
+}
+
+// passthroughNoRoute forwards the /v2 requests without a route of their own,
+// like /v2/usage or /v2/glossaries, in passthrough mode. Other requests are
+// left to the next NoRoute handler.
+func passthroughNoRoute(live *LiveConfig, pool *keyPool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		if !cfg.Passthrough || !strings.HasPrefix(c.Request.URL.Path, "/v2/") {
+			return
+		}
+		c.Set(routeKey, passthroughRoute)
+		if !authorize(c, cfg) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "Invalid access token",
+			})
+			c.Abort()
+			return
+		}
+		pool.forward(c, cfg)
+		c.Abort()
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 15:12:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 15:12:40
+ * @FilePath: /DeepLX/service/passthrough_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// officialAPI fakes the official DeepL API for passthrough
+type officialAPI struct {
+	*httptest.Server
+	mu        sync.Mutex
+	used      map[string]int
+	exhausted map[string]bool
+	requests  []*http.Request
+	// usageBlock holds /v2/usage requests until it is closed
+	usageBlock chan struct{}
+}
+
+func newOfficialAPI(t *testing.T) *officialAPI {
+	t.Helper()
+	api := &officialAPI{
+		used:      map[string]int{"low": 900, "high": 100},
+		exhausted: map[string]bool{},
+	}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		block := api.usageBlock
+		api.mu.Unlock()
+		if block != nil && r.URL.Path == "/v2/usage" {
+			<-block
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "DeepL-Auth-Key ")
+		if r.URL.Path != "/v2/usage" {
+			api.requests = append(api.requests, r.Clone(r.Context()))
+		}
+
+		used, ok := api.used[key]
+		switch {
+		case !ok:
+			http.Error(w, `{"message":"Wrong endpoint"}`, http.StatusForbidden)
+		case r.URL.Path == "/v2/usage":
+			fmt.Fprintf(w, `{"character_count":%d,"character_limit":1000}`, used)
+		case r.Method == http.MethodPost && r.URL.Path == "/v2/document":
+			fmt.Fprintf(w, `{"document_id":"doc-%s","document_key":"dockey"}`, key)
+		case strings.HasPrefix(r.URL.Path, "/v2/document/"):
+			// Documents are only known to the key that uploaded them
+			if id := strings.Split(r.URL.Path, "/")[3]; id != "doc-"+key {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message":"Document not found"}`)
+				return
+			}
+			fmt.Fprint(w, `{"status":"done"}`)
+		case api.exhausted[key]:
+			w.WriteHeader(456)
+			fmt.Fprint(w, `{"message":"Quota exceeded"}`)
+		default:
+			w.Header().Set("X-Trace-Id", "official")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"path":%q,"query":%q}`, r.URL.Path, r.URL.RawQuery)
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// last returns the last forwarded request
+func (api *officialAPI) last(t *testing.T) *http.Request {
+	t.Helper()
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.requests) == 0 {
+		t.Fatal("official API got no request")
+	}
+	return api.requests[len(api.requests)-1]
+}
+
+func TestPassthrough(t *testing.T) {
+	api := newOfficialAPI(t)
+	router, upstream := newTestRouter(t,
+		"-token", "secret",
+		"-passthrough",
+		"-passthrough-keys", "low,high",
+		"-passthrough-url", api.URL,
+	)
+	// The reverse proxy needs a real connection to notice clients going away
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	do := func(method, path, authorization string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(`{"text":["Hello"],"target_lang":"DE"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := do(http.MethodPost, "/v2/translate", "DeepL-Auth-Key secret")
+	if resp.StatusCode != http.StatusOK || body != `{"path":"/v2/translate","query":""}` || resp.Header.Get("X-Trace-Id") != "official" {
+		t.Errorf("POST /v2/translate = %d %s with headers %v, want the response of the API", resp.StatusCode, body, resp.Header)
+	}
+	if got := api.last(t).Header.Get("Authorization"); got != "DeepL-Auth-Key high" {
+		t.Errorf("Authorization = %q, want the key with the most characters left", got)
+	}
+	if n := len(upstream.Requests()); n != 0 {
+		t.Errorf("DeepL web endpoint got %d requests, want 0", n)
+	}
+
+	// Paths without a route of their own are forwarded too, without the token
+	resp, body = do(http.MethodGet, "/v2/languages?type=target&token=secret", "")
+	if resp.StatusCode != http.StatusOK || body != `{"path":"/v2/languages","query":"type=target"}` {
+		t.Errorf("GET /v2/languages = %d %s", resp.StatusCode, body)
+	}
+	if resp, _ = do(http.MethodGet, "/v2/languages", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /v2/languages without token = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// An exhausted key is avoided until its usage is checked again
+	api.mu.Lock()
+	api.exhausted["high"] = true
+	api.mu.Unlock()
+	for i, want := range []int{456, http.StatusOK} {
+		if resp, body = do(http.MethodGet, "/v2/glossaries?token=secret", ""); resp.StatusCode != want {
+			t.Errorf("request %d after the quota was used up = %d %s, want %d", i+1, resp.StatusCode, body, want)
+		}
+	}
+	if got := api.last(t).Header.Get("Authorization"); got != "DeepL-Auth-Key low" {
+		t.Errorf("Authorization = %q, want the other key", got)
+	}
+}
+
+func TestPassthroughDisabled(t *testing.T) {
+	router, _ := newTestRouter(t, "-passthrough-keys", "low")
+	w, body := serve(t, router, httptest.NewRequest(http.MethodGet, "/v2/usage", nil))
+	if w.Code != http.StatusNotFound || body["message"] != "Path not found" {
+		t.Errorf("GET /v2/usage = %d %v, want %d", w.Code, body, http.StatusNotFound)
+	}
+}
+
+func TestPassthroughPinsDocuments(t *testing.T) {
+	api := newOfficialAPI(t)
+	router, _ := newTestRouter(t,
+		"-passthrough",
+		"-passthrough-keys", "low,high",
+		"-passthrough-url", api.URL,
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	do := func(method, path string) (int, string, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), api.last(t).Header.Get("Authorization")
+	}
+
+	if code, body, key := do(http.MethodPost, "/v2/document"); code != http.StatusOK || key != "DeepL-Auth-Key high" {
+		t.Fatalf("POST /v2/document = %d %s with %q", code, body, key)
+	}
+
+	// The other key is picked once the uploading one ran out of characters
+	api.mu.Lock()
+	api.exhausted["high"] = true
+	api.mu.Unlock()
+	do(http.MethodPost, "/v2/translate")
+	if _, _, key := do(http.MethodPost, "/v2/translate"); key != "DeepL-Auth-Key low" {
+		t.Fatalf("POST /v2/translate used %q, want the other key", key)
+	}
+
+	// but the document is still polled with the key that uploaded it
+	for _, path := range []string{"/v2/document/doc-high", "/v2/document/doc-high/result"} {
+		if code, body, key := do(http.MethodPost, path); code != http.StatusOK || key != "DeepL-Auth-Key high" {
+			t.Errorf("POST %s = %d %s with %q, want the uploading key", path, code, body, key)
+		}
+	}
+}
+
+func TestPassthroughUsageInBackground(t *testing.T) {
+	api := newOfficialAPI(t)
+	router, _ := newTestRouter(t,
+		"-passthrough",
+		"-passthrough-keys", "low,high",
+		"-passthrough-url", api.URL,
+		"-passthrough-usage-interval", "10ms",
+	)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	translate := func() (string, time.Duration) {
+		t.Helper()
+		start := time.Now()
+		resp, err := http.Post(server.URL+"/v2/translate", "application/json", strings.NewReader(`{"text":["Hello"],"target_lang":"DE"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return api.last(t).Header.Get("Authorization"), time.Since(start)
+	}
+
+	// The first request waits for the usage of the keys
+	if key, _ := translate(); key != "DeepL-Auth-Key high" {
+		t.Fatalf("first request used %q, want the key with the most characters left", key)
+	}
+
+	// Later ones do not wait for /v2/usage
+	block := make(chan struct{})
+	api.mu.Lock()
+	api.usageBlock = block
+	api.used["high"], api.used["low"] = 950, 0
+	api.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	key, elapsed := translate()
+	if key != "DeepL-Auth-Key high" || elapsed > time.Second {
+		t.Errorf("request with stale usage used %q after %s, want the last known best key without waiting", key, elapsed)
+	}
+	close(block)
+
+	// and pick up the new usage once it is known
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if key, _ := translate(); key == "DeepL-Auth-Key low" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the usage checked in the background was not used")
+		}
+	}
+}
+
+func TestPassthroughUsageIntervalValidation(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	if err := fs.Parse([]string{"-passthrough-usage-interval", "0s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), "passthrough_usage_interval must be positive") {
+		t.Errorf("Load() error = %v, want the interval rejected", err)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 23:02:17
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:05:12
+ * @FilePath: /DeepLX/service/reload.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// configWatchInterval is how often the config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// LiveConfig holds the configuration of a running server. A reload swaps it
+// atomically, so every request sees either the old or the new configuration
+// as a whole. Requests should call Get once and keep using its result.
+type LiveConfig struct {
+	current atomic.Pointer[Config]
+	mu      sync.Mutex
+}
+
+// NewLiveConfig applies cfg and returns it as live configuration
+func NewLiveConfig(cfg *Config) *LiveConfig {
+	applyConfig(cfg)
+	live := &LiveConfig{}
+	live.current.Store(cfg)
+	return live
+}
+
+// Get returns the current configuration
+func (l *LiveConfig) Get() *Config {
+	return l.current.Load()
+}
+
+// Reload loads the configuration again and swaps it in. When the new
+// configuration is invalid the error is returned and the current one kept.
+func (l *LiveConfig) Reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.Get()
+	cfg, err := old.Reload()
+	if err != nil {
+		return err
+	}
+	applyConfig(cfg)
+
+	// The listener and the document store are set up once at startup
+	if cfg.IP != old.IP || cfg.Port != old.Port || cfg.DocumentDir != old.DocumentDir || cfg.DocumentWorkers != old.DocumentWorkers {
+		slog.Warn("ip, port, document_dir and document_workers take effect after a restart")
+	}
+
+	l.current.Store(cfg)
+	return nil
+}
+
+// Watch reloads the configuration on SIGHUP and, when watch_config is set,
+// whenever the config file changes, until ctx is done. Failed reloads are
+// logged and keep the current configuration.
+func (l *LiveConfig) Watch(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+	modTime := configModTime(l.Get())
+
+	reload := func(reason string) {
+		if err := l.Reload(); err != nil {
+			slog.Error("Failed to reload configuration, keeping the current one", "trigger", reason, "error", err)
+			return
+		}
+		slog.Info("Configuration reloaded", "trigger", reason)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			reload("SIGHUP")
+			modTime = configModTime(l.Get())
+		case <-ticker.C:
+			cfg := l.Get()
+			if !cfg.WatchConfig {
+				continue
+			}
+			if t := configModTime(cfg); !t.Equal(modTime) {
+				modTime = t
+				reload("config file change")
+			}
+		}
+	}
+}
+
+// configModTime returns the modification time of the config file of cfg, or
+// the zero time when there is none
+func configModTime(cfg *Config) time.Time {
+	if cfg.ConfigFile == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(cfg.ConfigFile)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// applyConfig applies the settings of cfg kept outside of it, the logger
+// and those of ApplyConfig. Proxies and dl-sessions are picked from the live
+// configuration on every request.
+func applyConfig(cfg *Config) {
+	setupLogging(cfg)
+	ApplyConfig(cfg)
+}
+
+// ApplyConfig applies the chunking, cooldown, detection and upstream of cfg to
+// the translate package. The commands call it instead of applyConfig to keep
+// the logger, which writes to stdout, off their output.
+func ApplyConfig(cfg *Config) {
+	translate.SetChunking(cfg.MaxTextLength, cfg.ChunkConcurrency)
+	translate.SetCooldown(time.Duration(cfg.Cooldown))
+	translate.SetDetectConfidence(cfg.DetectConfidence)
+	translate.SetUpstream(cfg.Upstream())
+}
@@ -0,0 +1,106 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 23:41:06
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 11:02:15
+ * @FilePath: /DeepLX/service/server.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Server is the HTTP server of DeepLX, serving the router of the live
+// configuration and shutting down gracefully
+type Server struct {
+	live    *LiveConfig
+	server  *http.Server
+	closers []func(ctx context.Context) error
+}
+
+// NewServer returns a server listening on the address of the live
+// configuration with its timeouts
+func NewServer(live *LiveConfig) (*Server, error) {
+	cfg := live.Get()
+	router, closers, err := newRouter(live)
+	if err != nil {
+		return nil, err
+	}
+	shutdownTracing, err := setupTracing(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		live: live,
+		server: &http.Server{
+			Addr:              fmt.Sprintf("%v:%v", cfg.IP, cfg.Port),
+			Handler:           router,
+			ReadHeaderTimeout: time.Duration(cfg.ReadTimeout),
+			ReadTimeout:       time.Duration(cfg.ReadTimeout),
+			WriteTimeout:      time.Duration(cfg.WriteTimeout),
+			IdleTimeout:       time.Duration(cfg.IdleTimeout),
+			ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		},
+	}
+	// Finish the running document translations and close the usage client,
+	// then flush the spans still buffered
+	for _, closer := range closers {
+		s.OnShutdown(closer)
+	}
+	if shutdownTracing != nil {
+		s.OnShutdown(shutdownTracing)
+	}
+	return s, nil
+}
+
+// OnShutdown registers fn to run once the server stopped serving requests,
+// to close clients and flush state before exiting
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.closers = append(s.closers, fn)
+}
+
+// Run serves requests until ctx is done. It then stops accepting
+// connections and waits for in-flight requests up to shutdown_timeout before
+// closing the remaining connections and running the OnShutdown functions,
+// each given shutdown_timeout of its own.
+func (s *Server) Run(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := time.Duration(s.live.Get().ShutdownTimeout)
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.server.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("In-flight requests did not finish in time, closing their connections")
+		err = s.server.Close()
+	}
+	for _, closer := range s.closers {
+		closeCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = errors.Join(err, closer(closeCtx))
+		cancel()
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-23 11:02:15
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 11:02:15
+ * @FilePath: /DeepLX/service/server_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServerShutdownClosers(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	if err := fs.Parse([]string{
+		"-ip", "127.0.0.1",
+		"-port", fmt.Sprint(port),
+		"-shutdown-timeout", "50ms",
+		"-document-dir", t.TempDir(),
+		"-log-level", "error",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer(NewLiveConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	s.server.Handler.(*gin.Engine).GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+	})
+	closerErr := make(chan error, 1)
+	s.OnShutdown(func(ctx context.Context) error {
+		closerErr <- ctx.Err()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	go func() {
+		for {
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/slow", port))
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			select {
+			case <-started:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request did not start")
+	}
+
+	// The slow request outlives shutdown_timeout, the closers still get time
+	cancel()
+	<-done
+	if err := <-closerErr; err != nil {
+		t.Errorf("closer got a context that was already done: %v", err)
+	}
+}
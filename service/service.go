@@ -13,51 +13,77 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"github.com/OwO-Network/DeepLX/document"
+	"github.com/OwO-Network/DeepLX/metrics"
 	"github.com/OwO-Network/DeepLX/translate"
 )
 
-func authMiddleware(cfg *Config) gin.HandlerFunc {
+func authMiddleware(live *LiveConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if cfg.Token != "" {
-			providedTokenInQuery := c.Query("token")
-			providedTokenInHeader := c.GetHeader("Authorization")
-
-			// Compatability with the Bearer token format
-			if providedTokenInHeader != "" {
-				parts := strings.Split(providedTokenInHeader, " ")
-				if len(parts) == 2 {
-					if parts[0] == "Bearer" || parts[0] == "DeepL-Auth-Key" {
-						providedTokenInHeader = parts[1]
-					} else {
-						providedTokenInHeader = ""
-					}
-				} else {
-					providedTokenInHeader = ""
-				}
-			}
+		if !authorize(c, live.Get()) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "Invalid access token",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
 
-			if providedTokenInHeader != cfg.Token && providedTokenInQuery != cfg.Token {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"code":    http.StatusUnauthorized,
-					"message": "Invalid access token",
-				})
-				c.Abort()
-				return
+// authorize reports whether the request carries an accepted access token,
+// or whether none is required, and remembers the token of the caller
+func authorize(c *gin.Context, cfg *Config) bool {
+	tokens := cfg.tokens()
+	if len(tokens) == 0 {
+		return true
+	}
+	providedTokenInQuery := c.Query("token")
+	providedTokenInHeader := c.GetHeader("Authorization")
+
+	// Compatability with the Bearer token format
+	if providedTokenInHeader != "" {
+		parts := strings.Split(providedTokenInHeader, " ")
+		if len(parts) == 2 {
+			if parts[0] == "Bearer" || parts[0] == "DeepL-Auth-Key" {
+				providedTokenInHeader = parts[1]
+			} else {
+				providedTokenInHeader = ""
 			}
+		} else {
+			providedTokenInHeader = ""
 		}
+	}
 
-		c.Next()
+	for _, token := range tokens {
+		if providedTokenInHeader == token || providedTokenInQuery == token {
+			c.Set(tokenKey, token)
+			annotate(c, slog.String("caller", fingerprint(token)))
+			return true
+		}
 	}
+	return false
+}
+
+// validTagHandling reports whether tagHandling is empty or a supported value
+func validTagHandling(tagHandling string) bool {
+	switch tagHandling {
+	case "", "html", "xml", "markdown":
+		return true
+	}
+	return false
 }
 
 type PayloadFree struct {
@@ -65,6 +91,53 @@ type PayloadFree struct {
 	SourceLang  string `json:"source_lang"`
 	TargetLang  string `json:"target_lang"`
 	TagHandling string `json:"tag_handling"`
+	// SourceLangHints are the likely source languages when source_lang is
+	// auto or empty
+	SourceLangHints []string `json:"source_lang_hints"`
+
+	PreservePlaceholders bool     `json:"preserve_placeholders"`
+	PlaceholderStyles    []string `json:"placeholder_styles"`
+	PlaceholderPatterns  []string `json:"placeholder_patterns"`
+	PlaceholderCheck     string   `json:"placeholder_check"` // "warn" (default) or "fail"
+}
+
+// translatePayload translates a /translate or /v1/translate request with t,
+// protecting placeholders when the request asks for it
+func translatePayload(ctx context.Context, t translate.Translator, req PayloadFree, proxyURL string, dlSession string) (translate.DeepLXTranslationResult, error) {
+	request := translate.Request{
+		SourceLang:      req.SourceLang,
+		SourceLangHints: req.SourceLangHints,
+		TargetLang:      req.TargetLang,
+		Text:            req.TransText,
+		TagHandling:     req.TagHandling,
+		ProxyURL:        proxyURL,
+		DlSession:       dlSession,
+	}
+	if !req.PreservePlaceholders {
+		return t.Translate(ctx, request)
+	}
+	return translate.TranslateWithPlaceholders(ctx, t, request, translate.PlaceholderOptions{
+		Styles:   req.PlaceholderStyles,
+		Patterns: req.PlaceholderPatterns,
+		Strict:   req.PlaceholderCheck == "fail",
+	})
+}
+
+// translationResponse renders a successful /translate or /v1/translate result
+func translationResponse(result translate.DeepLXTranslationResult) gin.H {
+	response := gin.H{
+		"code":         http.StatusOK,
+		"id":           result.ID,
+		"data":         result.Data,
+		"alternatives": result.Alternatives,
+		"source_lang":  result.SourceLang,
+		"target_lang":  result.TargetLang,
+		"method":       result.Method,
+	}
+	if len(result.Warnings) > 0 {
+		response["warnings"] = result.Warnings
+	}
+	return response
 }
 
 type PayloadAPI struct {
@@ -74,27 +147,35 @@ type PayloadAPI struct {
 	TagHandling string   `json:"tag_handling"`
 }
 
-func Router(cfg *Config) *gin.Engine {
+// Router returns the HTTP handler serving the live configuration
+func Router(live *LiveConfig) (*gin.Engine, error) {
+	r, _, err := newRouter(live)
+	return r, err
+}
+
+// newRouter returns the HTTP handler serving the live configuration, and
+// the functions closing its background work on shutdown
+func newRouter(live *LiveConfig) (*gin.Engine, []func(ctx context.Context) error, error) {
+	cfg := live.Get()
+
 	// Set Proxy
-	proxyURL := os.Getenv("PROXY")
-	if proxyURL == "" {
-		proxyURL = cfg.Proxy
-	}
-	if proxyURL != "" {
-		proxy, err := url.Parse(proxyURL)
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
 		if err != nil {
-			log.Fatalf("Failed to parse proxy URL: %v", err)
+			return nil, nil, fmt.Errorf("failed to parse proxy URL: %w", err)
 		}
 		http.DefaultTransport = &http.Transport{
 			Proxy: http.ProxyURL(proxy),
 		}
 	}
 
-	if cfg.Token != "" {
-		fmt.Println("Access token is set.")
+	if len(cfg.tokens()) > 0 {
+		slog.Info("Access token is set")
 	}
 
-	r := gin.Default()
+	r := gin.New()
+	// Metrics sit outside the recovery, which answers panics with a 500
+	r.Use(loggingMiddleware, metricsMiddleware, recoveryMiddleware, tracingMiddleware)
 	r.Use(cors.Default())
 
 	// Defining the root endpoint which returns the project details
@@ -105,41 +186,41 @@ func Router(cfg *Config) *gin.Engine {
 		})
 	})
 
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Liveness and readiness probes
+	r.GET("/healthz", healthzHandler)
+	r.GET("/readyz", readyzHandler(live))
+
 	// Free API endpoint, No Pro Account required
-	r.POST("/translate", authMiddleware(cfg), func(c *gin.Context) {
+	r.POST("/translate", authMiddleware(live), func(c *gin.Context) {
 		req := PayloadFree{}
 		c.BindJSON(&req)
 
-		sourceLang := req.SourceLang
-		targetLang := req.TargetLang
-		translateText := req.TransText
 		tagHandling := req.TagHandling
 
-		proxyURL := cfg.Proxy
+		cfg := live.Get()
+		proxyURL := cfg.NextProxy()
 
-		if tagHandling != "" && tagHandling != "html" && tagHandling != "xml" {
+		if !validTagHandling(tagHandling) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    http.StatusBadRequest,
-				"message": "Invalid tag_handling value. Allowed values are 'html' and 'xml'.",
+				"message": "Invalid tag_handling value. Allowed values are 'html', 'xml' and 'markdown'.",
 			})
 			return
 		}
 
-		result, err := translate.TranslateByDeepLX(sourceLang, targetLang, translateText, tagHandling, proxyURL, "")
+		start := time.Now()
+		result, err := translatePayload(c.Request.Context(), requestTranslator(c, cfg), req, proxyURL, "")
+		logTranslation(c, cfg, req.SourceLang, req.TargetLang, req.TransText, result, proxyURL, time.Since(start))
 		if err != nil {
-			log.Fatalf("Translation failed: %s", err)
+			translationFailed(c, err)
+			return
 		}
 
 		if result.Code == http.StatusOK {
-			c.JSON(http.StatusOK, gin.H{
-				"code":         http.StatusOK,
-				"id":           result.ID,
-				"data":         result.Data,
-				"alternatives": result.Alternatives,
-				"source_lang":  result.SourceLang,
-				"target_lang":  result.TargetLang,
-				"method":       result.Method,
-			})
+			c.JSON(http.StatusOK, translationResponse(result))
 		} else {
 			c.JSON(result.Code, gin.H{
 				"code":    result.Code,
@@ -149,23 +230,33 @@ func Router(cfg *Config) *gin.Engine {
 		}
 	})
 
+	// Streaming endpoint, translates long texts paragraph by paragraph as Server-Sent Events
+	r.POST("/translate/stream", authMiddleware(live), streamHandler(live))
+
+	// Subtitle endpoint, translates SRT and WebVTT cues keeping their timing
+	r.POST("/translate/subtitle", authMiddleware(live), subtitleHandler(live))
+
+	// Localization endpoint, translates the values of JSON, YAML, gettext, Android, iOS and XLIFF files
+	r.POST("/translate/localization", authMiddleware(live), localizationHandler(live))
+
+	// Language detection endpoint, runs locally unless asked to confirm upstream
+	r.POST("/detect", authMiddleware(live), detectHandler(live))
+
 	// Pro API endpoint, Pro Account required
-	r.POST("/v1/translate", authMiddleware(cfg), func(c *gin.Context) {
+	r.POST("/v1/translate", authMiddleware(live), func(c *gin.Context) {
 		req := PayloadFree{}
 		c.BindJSON(&req)
 
-		sourceLang := req.SourceLang
-		targetLang := req.TargetLang
-		translateText := req.TransText
 		tagHandling := req.TagHandling
-		proxyURL := cfg.Proxy
+		cfg := live.Get()
+		proxyURL := cfg.NextProxy()
 
-		dlSession := cfg.DlSession
+		dlSession := cfg.NextDlSession()
 
-		if tagHandling != "" && tagHandling != "html" && tagHandling != "xml" {
+		if !validTagHandling(tagHandling) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"code":    http.StatusBadRequest,
-				"message": "Invalid tag_handling value. Allowed values are 'html' and 'xml'.",
+				"message": "Invalid tag_handling value. Allowed values are 'html', 'xml' and 'markdown'.",
 			})
 			return
 		}
@@ -189,21 +280,16 @@ func Router(cfg *Config) *gin.Engine {
 			return
 		}
 
-		result, err := translate.TranslateByDeepLX(sourceLang, targetLang, translateText, tagHandling, proxyURL, dlSession)
+		start := time.Now()
+		result, err := translatePayload(c.Request.Context(), requestTranslator(c, cfg), req, proxyURL, dlSession)
+		logTranslation(c, cfg, req.SourceLang, req.TargetLang, req.TransText, result, proxyURL, time.Since(start))
 		if err != nil {
-			log.Fatalf("Translation failed: %s", err)
+			translationFailed(c, err)
+			return
 		}
 
 		if result.Code == http.StatusOK {
-			c.JSON(http.StatusOK, gin.H{
-				"code":         http.StatusOK,
-				"id":           result.ID,
-				"data":         result.Data,
-				"alternatives": result.Alternatives,
-				"source_lang":  result.SourceLang,
-				"target_lang":  result.TargetLang,
-				"method":       result.Method,
-			})
+			c.JSON(http.StatusOK, translationResponse(result))
 		} else {
 			c.JSON(result.Code, gin.H{
 				"code":    result.Code,
@@ -213,9 +299,14 @@ func Router(cfg *Config) *gin.Engine {
 		}
 	})
 
+	// Official API passthrough, forwarding /v2 requests with server-held keys
+	pool := newKeyPool()
+	passthrough := passthroughMiddleware(live, pool)
+
 	// Free API endpoint, Consistent with the official API format
-	r.POST("/v2/translate", authMiddleware(cfg), func(c *gin.Context) {
-		proxyURL := cfg.Proxy
+	r.POST("/v2/translate", authMiddleware(live), passthrough, func(c *gin.Context) {
+		cfg := live.Get()
+		proxyURL := cfg.NextProxy()
 
 		var translateText string
 		var targetLang string
@@ -241,9 +332,16 @@ func Router(cfg *Config) *gin.Engine {
 			targetLang = jsonData.TargetLang
 		}
 
-		result, err := translate.TranslateByDeepLX("", targetLang, translateText, "", proxyURL, "")
+		start := time.Now()
+		result, err := requestTranslator(c, cfg).Translate(c.Request.Context(), translate.Request{
+			TargetLang: targetLang,
+			Text:       translateText,
+			ProxyURL:   proxyURL,
+		})
+		logTranslation(c, cfg, "", targetLang, translateText, result, proxyURL, time.Since(start))
 		if err != nil {
-			log.Fatalf("Translation failed: %s", err)
+			translationFailed(c, err)
+			return
 		}
 
 		if result.Code == http.StatusOK {
@@ -263,13 +361,34 @@ func Router(cfg *Config) *gin.Engine {
 		}
 	})
 
-	// Catch-all route to handle undefined paths
-	r.NoRoute(func(c *gin.Context) {
+	// DeepL Write endpoint, Consistent with the official API format
+	r.POST("/v2/write/rephrase", authMiddleware(live), passthrough, writeHandler(live))
+
+	// Document translation endpoints, Consistent with the official API format
+	documents, err := document.NewStore(cfg.DocumentDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open document store: %w", err)
+	}
+	worker := newDocumentWorker(live, documents)
+	if pending, err := documents.Pending(); err == nil {
+		for _, job := range pending {
+			worker.enqueue(job)
+		}
+	}
+	r.POST("/v2/document", authMiddleware(live), passthrough, documentUploadHandler(worker))
+	r.POST("/v2/document/:id", authMiddleware(live), passthrough, documentStatusHandler(documents))
+	r.GET("/v2/document/:id", authMiddleware(live), passthrough, documentStatusHandler(documents))
+	r.POST("/v2/document/:id/result", authMiddleware(live), passthrough, documentResultHandler(documents))
+	r.GET("/v2/document/:id/result", authMiddleware(live), passthrough, documentResultHandler(documents))
+
+	// Catch-all route to handle undefined paths, and the remaining official
+	// API paths in passthrough mode
+	r.NoRoute(passthroughNoRoute(live, pool), func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    http.StatusNotFound,
 			"message": "Path not found",
 		})
 	})
 
-	return r
+	return r, []func(ctx context.Context) error{worker.close, pool.close}, nil
 }
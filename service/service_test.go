@@ -0,0 +1,473 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 15:20:33
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 15:20:33
+ * @FilePath: /DeepLX/service/service_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/fakedeepl"
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestRouter starts a fake DeepL upstream and returns it together with a
+// router sending its upstream requests there. args are flags of
+// "deeplx serve".
+func newTestRouter(t *testing.T, args ...string) (*gin.Engine, *fakedeepl.Server) {
+	t.Helper()
+
+	upstream := fakedeepl.New()
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { translate.SetUpstream(translate.Upstream{}) })
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := NewConfig(fs)
+	args = append([]string{
+		"-upstream-url", server.URL + "/jsonrpc",
+		"-document-dir", t.TempDir(),
+		"-log-level", "error",
+	}, args...)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	router, err := Router(NewLiveConfig(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, upstream
+}
+
+// serve sends req to router and returns the response with its JSON body
+func serve(t *testing.T, router http.Handler, req *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: invalid JSON response %q", req.Method, req.URL, w.Body.String())
+	}
+	return w, body
+}
+
+// lastLogEntry sends req to router and returns the access log entry of the
+// request
+func lastLogEntry(t *testing.T, router http.Handler, req *http.Request) map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&out, nil)))
+	defer slog.SetDefault(previous)
+
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	var entry map[string]any
+	if err := json.Unmarshal(lines[len(lines)-1], &entry); err != nil {
+		t.Fatalf("invalid log entry %q", out.String())
+	}
+	return entry
+}
+
+// postJSON returns a POST request of body to path
+func postJSON(path string, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestTranslate(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	w, body := serve(t, router, postJSON("/translate", `{"text":"Hello world","source_lang":"EN","target_lang":"DE"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if body["data"] != "[DE] Hello world" {
+		t.Errorf("data = %q", body["data"])
+	}
+	if alternatives, _ := body["alternatives"].([]any); len(alternatives) != 3 || alternatives[0] != "[DE] Hello world (1)" {
+		t.Errorf("alternatives = %v", body["alternatives"])
+	}
+	if body["source_lang"] != "EN" || body["target_lang"] != "DE" || body["method"] != "Free" {
+		t.Errorf("source_lang = %v, target_lang = %v, method = %v", body["source_lang"], body["target_lang"], body["method"])
+	}
+
+	requests := upstream.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("upstream got %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Method != "LMT_handle_texts" || got.TargetLang != "DE" || len(got.Texts) != 1 || got.Texts[0] != "Hello world" {
+		t.Errorf("upstream request = %+v", got)
+	}
+	if got.Header.Get("User-Agent") != translate.DefaultUserAgent {
+		t.Errorf("User-Agent = %q", got.Header.Get("User-Agent"))
+	}
+	if got.Header.Get("Cookie") != "" {
+		t.Errorf("Cookie = %q, want none", got.Header.Get("Cookie"))
+	}
+}
+
+func TestTranslateDetectsSourceLang(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w, body := serve(t, router, postJSON("/translate", `{"text":"Das ist ein ganz normaler deutscher Satz.","target_lang":"EN"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if body["source_lang"] != "DE" {
+		t.Errorf("source_lang = %v, want DE", body["source_lang"])
+	}
+}
+
+func TestTranslateSourceLangHints(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	// Too short to detect, DeepL is left to it unless hinted
+	for _, tt := range []struct {
+		body string
+		want string
+	}{
+		{`{"text":"Hello","target_lang":"DE"}`, "auto"},
+		{`{"text":"Hello","target_lang":"DE","source_lang_hints":["EN"]}`, "EN"},
+	} {
+		upstream.Reset()
+		if w, _ := serve(t, router, postJSON("/translate", tt.body)); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body)
+		}
+		if got := upstream.Requests()[0].SourceLang; got != tt.want {
+			t.Errorf("%s: upstream source language = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateEmptyText(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	w, body := serve(t, router, postJSON("/translate", `{"text":"","target_lang":"DE"}`))
+	if w.Code != http.StatusNotFound || body["message"] != "No text to translate" {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+	if n := len(upstream.Requests()); n != 0 {
+		t.Errorf("upstream got %d requests, want 0", n)
+	}
+}
+
+func TestTranslateTagHandling(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	tests := []struct {
+		tagHandling string
+		text        string
+		status      int
+	}{
+		{"", "Hello", http.StatusOK},
+		{"html", "<p>Hello</p>", http.StatusOK},
+		{"xml", "<p>Hello</p>", http.StatusOK},
+		{"markdown", "# Hello", http.StatusOK},
+		{"HTML", "<p>Hello</p>", http.StatusBadRequest},
+		{"json", "Hello", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/translate", "/v1/translate"} {
+			payload, _ := json.Marshal(map[string]string{
+				"text":         tt.text,
+				"source_lang":  "EN",
+				"target_lang":  "DE",
+				"tag_handling": tt.tagHandling,
+			})
+			req := postJSON(path, string(payload))
+			req.Header.Set("Cookie", "dl_session=session")
+			w, _ := serve(t, router, req)
+			if w.Code != tt.status {
+				t.Errorf("%s with tag_handling %q: status = %d, want %d, body %s", path, tt.tagHandling, w.Code, tt.status, w.Body)
+			}
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	router, _ := newTestRouter(t, "-token", "secret", "-api-keys", "key1,key2")
+
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		status int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"bearer", "Bearer secret", "", http.StatusOK},
+		{"deepl auth key", "DeepL-Auth-Key secret", "", http.StatusOK},
+		{"api key", "Bearer key2", "", http.StatusOK},
+		{"query", "", "secret", http.StatusOK},
+		{"wrong token", "Bearer wrong", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic secret", "", http.StatusUnauthorized},
+		{"bare token", "secret", "", http.StatusUnauthorized},
+		{"wrong query", "", "wrong", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/translate", "/v2/translate"} {
+			target := path
+			if tt.query != "" {
+				target += "?token=" + url.QueryEscape(tt.query)
+			}
+			req := postJSON(target, `{"text":["Hello"],"target_lang":"DE"}`)
+			if path == "/translate" {
+				req = postJSON(target, `{"text":"Hello","target_lang":"DE"}`)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w, body := serve(t, router, req)
+			if w.Code != tt.status {
+				t.Errorf("%s %s: status = %d, want %d", tt.name, path, w.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && body["message"] != "Invalid access token" {
+				t.Errorf("%s %s: message = %v", tt.name, path, body["message"])
+			}
+		}
+	}
+}
+
+func TestV1TranslateDlSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		cookie  string
+		status  int
+		session string
+	}{
+		{"missing", nil, "", http.StatusUnauthorized, ""},
+		{"free account", nil, "dl_session=abc.def", http.StatusUnauthorized, ""},
+		{"cookie", nil, "dl_session=pro", http.StatusOK, "pro"},
+		{"configured", []string{"-s", "configured"}, "", http.StatusOK, "configured"},
+		{"cookie overrides configured", []string{"-s", "configured"}, "dl_session=pro", http.StatusOK, "pro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, upstream := newTestRouter(t, tt.args...)
+
+			req := postJSON("/v1/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`)
+			if tt.cookie != "" {
+				req.Header.Set("Cookie", tt.cookie)
+			}
+			w, body := serve(t, router, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				if n := len(upstream.Requests()); n != 0 {
+					t.Errorf("upstream got %d requests, want 0", n)
+				}
+				return
+			}
+
+			if body["method"] != "Pro" {
+				t.Errorf("method = %v, want Pro", body["method"])
+			}
+			requests := upstream.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("upstream got %d requests, want 1", len(requests))
+			}
+			if cookie := requests[0].Header.Get("Cookie"); cookie != "dl_session="+tt.session {
+				t.Errorf("upstream Cookie = %q, want dl_session=%s", cookie, tt.session)
+			}
+		})
+	}
+}
+
+func TestV2Translate(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	form := url.Values{"text": {"Bonjour tout le monde"}, "target_lang": {"EN"}}
+	req := httptest.NewRequest(http.MethodPost, "/v2/translate", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w, body := serve(t, router, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("form: status = %d, body %s", w.Code, w.Body)
+	}
+	translations, _ := body["translations"].([]any)
+	if len(translations) != 1 {
+		t.Fatalf("form: translations = %v", body["translations"])
+	}
+	translation, _ := translations[0].(map[string]any)
+	if translation["text"] != "[EN] Bonjour tout le monde" || translation["detected_source_language"] != "FR" {
+		t.Errorf("form: translation = %v", translation)
+	}
+
+	w, body = serve(t, router, postJSON("/v2/translate", `{"text":["Hello","World"],"target_lang":"DE"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("json: status = %d, body %s", w.Code, w.Body)
+	}
+	translations, _ = body["translations"].([]any)
+	if len(translations) != 1 {
+		t.Fatalf("json: translations = %v", body["translations"])
+	}
+	if translation, _ := translations[0].(map[string]any); translation["text"] != "[DE] Hello\nWorld" {
+		t.Errorf("json: translation = %v", translation)
+	}
+
+	if n := len(upstream.Requests()); n != 2 {
+		t.Errorf("upstream got %d requests, want 2", n)
+	}
+
+	w, body = serve(t, router, postJSON("/v2/translate", `{"text":`))
+	if w.Code != http.StatusBadRequest || body["message"] != "Invalid request payload" {
+		t.Errorf("invalid payload: status = %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestUpstreamEncodings(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	tests := []struct {
+		encoding string
+		hidden   bool
+	}{
+		{"", false},
+		{"gzip", false},
+		{"br", false},
+		{"deflate", false},
+		{"zstd", false},
+		{"gzip, br", false},
+		{"deflate, zstd", false},
+		{"gzip", true},
+		{"zstd", true},
+		{"br", true},
+	}
+	for _, tt := range tests {
+		upstream.SetEncoding(tt.encoding)
+		upstream.HideEncoding(tt.hidden)
+		w, body := serve(t, router, postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`))
+		if w.Code != http.StatusOK || body["data"] != "[DE] Hello" {
+			t.Errorf("encoding %q, hidden %v: status = %d, body %s", tt.encoding, tt.hidden, w.Code, w.Body)
+		}
+	}
+}
+
+func TestUpstreamFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		rateLimit bool
+		status    int
+		message   string
+	}{
+		{"rate limited", "Hello", true, http.StatusTooManyRequests, "too many requests"},
+		{"rate limited text", fakedeepl.TextRateLimited, false, http.StatusTooManyRequests, "too many requests"},
+		{"malformed JSON", fakedeepl.TextMalformed, false, http.StatusServiceUnavailable, "invalid JSON response"},
+		{"empty result", fakedeepl.TextEmpty, false, http.StatusServiceUnavailable, "Translation failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, upstream := newTestRouter(t)
+			for _, encoding := range []string{"", "gzip", "zstd"} {
+				upstream.SetEncoding(encoding)
+				if tt.rateLimit {
+					upstream.RateLimit(1)
+				}
+
+				payload, _ := json.Marshal(map[string]string{"text": tt.text, "source_lang": "EN", "target_lang": "DE"})
+				w, body := serve(t, router, postJSON("/translate", string(payload)))
+				if w.Code != tt.status {
+					t.Errorf("encoding %q: status = %d, want %d", encoding, w.Code, tt.status)
+				}
+				if message, _ := body["message"].(string); !strings.Contains(strings.ToLower(message), strings.ToLower(tt.message)) {
+					t.Errorf("encoding %q: message = %q, want it to contain %q", encoding, message, tt.message)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int64
+		message string
+		data    any
+		status  int
+		want    string
+	}{
+		{"rate limited", 1042912, "Too many requests", nil, http.StatusTooManyRequests, "Too many requests"},
+		{"unsupported language", -32600, "Invalid Request", map[string]string{"what": "Value for 'params.lang.target_lang' not supported."}, http.StatusBadRequest, "not supported"},
+		{"invalid params", -32602, "Invalid params", nil, http.StatusBadRequest, "Invalid params"},
+		{"quota exceeded", 1156049, "Quota exceeded", nil, translate.StatusQuotaExceeded, "Quota exceeded"},
+		{"unknown", 4711, "Something went wrong", "details", http.StatusBadGateway, "Something went wrong (details)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, upstream := newTestRouter(t, "-s", "pro")
+			upstream.SetError(tt.code, tt.message, tt.data)
+
+			for _, req := range []*http.Request{
+				postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`),
+				postJSON("/v1/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`),
+				postJSON("/v2/translate", `{"text":["Hello"],"target_lang":"DE"}`),
+			} {
+				w, body := serve(t, router, req)
+				if w.Code != tt.status {
+					t.Errorf("%s: status = %d, want %d", req.URL.Path, w.Code, tt.status)
+				}
+				if message, _ := body["message"].(string); !strings.Contains(message, tt.want) {
+					t.Errorf("%s: message = %q, want it to contain %q", req.URL.Path, message, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamSettings(t *testing.T) {
+	router, upstream := newTestRouter(t, "-user-agent", "DeepLX-Test/1.0")
+
+	w, _ := serve(t, router, postJSON("/translate", `{"text":"Hello","source_lang":"EN","target_lang":"DE"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	requests := upstream.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("upstream got %d requests, want 1", len(requests))
+	}
+	if ua := requests[0].Header.Get("User-Agent"); ua != "DeepLX-Test/1.0" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w, body := serve(t, router, httptest.NewRequest(http.MethodGet, "/nothing", nil))
+	if w.Code != http.StatusNotFound || body["message"] != "Path not found" {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 10:12:40
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 11:25:50
+ * @FilePath: /DeepLX/service/stream.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+type PayloadStream struct {
+	TransText       string   `json:"text"`
+	SourceLang      string   `json:"source_lang"`
+	SourceLangHints []string `json:"source_lang_hints"`
+	TargetLang      string   `json:"target_lang"`
+	TagHandling     string   `json:"tag_handling"`
+	Concurrency     int      `json:"concurrency"`
+}
+
+// streamChunk is the data of a "chunk" event
+type streamChunk struct {
+	Index        int      `json:"index"`
+	Data         string   `json:"data"`
+	Alternatives []string `json:"alternatives"`
+	SourceLang   string   `json:"source_lang"`
+	TargetLang   string   `json:"target_lang"`
+}
+
+// streamError is the data of an "error" event, it carries the original
+// paragraph so that the client can retry only the failed part
+type streamError struct {
+	Index   int    `json:"index"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Text    string `json:"text"`
+}
+
+// streamSummary is the data of the final "done" event
+type streamSummary struct {
+	Total         int    `json:"total"`
+	Succeeded     int    `json:"succeeded"`
+	Failed        int    `json:"failed"`
+	FailedIndices []int  `json:"failed_indices"`
+	SourceLang    string `json:"source_lang"`
+	TargetLang    string `json:"target_lang"`
+	// Method is the method of the translations, comma separated when the
+	// paragraphs were served by several, like through a fallback backend
+	Method string `json:"method"`
+}
+
+var paragraphSeparator = regexp.MustCompile(`\n\s*\n`)
+
+// splitParagraphs splits text on blank lines and drops empty paragraphs
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, p := range paragraphSeparator.Split(strings.ReplaceAll(text, "\r\n", "\n"), -1) {
+		if strings.TrimSpace(p) != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// streamHandler translates the paragraphs of a long text concurrently and
+// emits every paragraph as a Server-Sent Event as soon as it is ready
+func streamHandler(live *LiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		req := PayloadStream{}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid request payload",
+			})
+			return
+		}
+
+		if !validTagHandling(req.TagHandling) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid tag_handling value. Allowed values are 'html', 'xml' and 'markdown'.",
+			})
+			return
+		}
+
+		paragraphs := splitParagraphs(req.TransText)
+		if len(paragraphs) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "No text to translate",
+			})
+			return
+		}
+
+		translator := requestTranslator(c, cfg)
+		concurrency := cfg.StreamConcurrency
+		if req.Concurrency > 0 && req.Concurrency < concurrency {
+			concurrency = req.Concurrency
+		}
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		type indexedResult struct {
+			index  int
+			result translate.DeepLXTranslationResult
+		}
+
+		ctx := c.Request.Context()
+		results := make(chan indexedResult)
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+
+		go func() {
+			defer close(results)
+			for i, paragraph := range paragraphs {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					wg.Wait()
+					return
+				}
+				wg.Add(1)
+				go func(i int, paragraph string) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					result, err := translator.Translate(ctx, translate.Request{
+						SourceLang:      req.SourceLang,
+						SourceLangHints: req.SourceLangHints,
+						TargetLang:      req.TargetLang,
+						Text:            paragraph,
+						TagHandling:     req.TagHandling,
+						ProxyURL:        cfg.NextProxy(),
+					})
+					if err != nil {
+						result = translate.DeepLXTranslationResult{
+							Code:    http.StatusServiceUnavailable,
+							Message: err.Error(),
+						}
+					}
+					select {
+					case results <- indexedResult{index: i, result: result}:
+					case <-ctx.Done():
+					}
+				}(i, paragraph)
+			}
+			wg.Wait()
+		}()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		summary := streamSummary{
+			Total:         len(paragraphs),
+			FailedIndices: []int{},
+			SourceLang:    req.SourceLang,
+			TargetLang:    req.TargetLang,
+		}
+		// methods are those of the results that served the paragraphs
+		var methods []string
+
+		for r := range results {
+			if r.result.Code == http.StatusOK {
+				summary.Succeeded++
+				summary.SourceLang = r.result.SourceLang
+				if r.result.Method != "" && !slices.Contains(methods, r.result.Method) {
+					methods = append(methods, r.result.Method)
+				}
+				c.SSEvent("chunk", streamChunk{
+					Index:        r.index,
+					Data:         r.result.Data,
+					Alternatives: r.result.Alternatives,
+					SourceLang:   r.result.SourceLang,
+					TargetLang:   r.result.TargetLang,
+				})
+			} else {
+				summary.Failed++
+				summary.FailedIndices = append(summary.FailedIndices, r.index)
+				c.SSEvent("error", streamError{
+					Index:   r.index,
+					Code:    r.result.Code,
+					Message: r.result.Message,
+					Text:    paragraphs[r.index],
+				})
+			}
+			c.Writer.Flush()
+		}
+
+		outcome := "ok"
+		if summary.Failed > 0 {
+			outcome = fmt.Sprintf("%d of %d paragraphs failed", summary.Failed, summary.Total)
+		}
+		annotate(c,
+			slog.String("source_lang", summary.SourceLang),
+			slog.String("target_lang", req.TargetLang),
+			slog.Int("characters", utf8.RuneCountInString(req.TransText)),
+			slog.String("outcome", outcome),
+		)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		slices.Sort(methods)
+		summary.Method = strings.Join(methods, ",")
+		c.SSEvent("done", summary)
+		c.Writer.Flush()
+	}
+}
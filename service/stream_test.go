@@ -0,0 +1,106 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 14:05:11
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 14:05:11
+ * @FilePath: /DeepLX/service/stream_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OwO-Network/DeepLX/fakedeepl"
+)
+
+// sseEvent is an event of a Server-Sent Events stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// parseEvents returns the events of an SSE body in order
+func parseEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event sseEvent
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event.name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				event.data = strings.TrimPrefix(line, "data:")
+			}
+		}
+		if event.name == "" {
+			t.Fatalf("event without name in %q", body)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestStream(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	text := "First paragraph.\n\n" + fakedeepl.TextRateLimited + "\n \nThird paragraph."
+	payload, _ := json.Marshal(map[string]any{
+		"text":        text,
+		"source_lang": "EN",
+		"target_lang": "DE",
+		"concurrency": 1,
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, postJSON("/translate/stream", string(payload)))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status = %d, Content-Type %q, body %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	// A single worker translates the paragraphs in order
+	events := parseEvents(t, w.Body.String())
+	var names []string
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if got := strings.Join(names, ","); got != "chunk,error,chunk,done" {
+		t.Fatalf("events = %s, want chunk,error,chunk,done", got)
+	}
+
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(events[2].data), &chunk); err != nil || chunk.Index != 2 || chunk.Data != "[DE] Third paragraph." {
+		t.Errorf("last chunk = %+v (%v), want the third paragraph", chunk, err)
+	}
+	var failure streamError
+	if err := json.Unmarshal([]byte(events[1].data), &failure); err != nil {
+		t.Fatal(err)
+	}
+	if failure.Index != 1 || failure.Code != http.StatusTooManyRequests || failure.Text != fakedeepl.TextRateLimited {
+		t.Errorf("error event = %+v, want the rate limited paragraph", failure)
+	}
+	var summary streamSummary
+	if err := json.Unmarshal([]byte(events[3].data), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 || len(summary.FailedIndices) != 1 || summary.FailedIndices[0] != 1 || summary.Method != "Free" {
+		t.Errorf("summary = %+v", summary)
+	}
+	if n := len(upstream.Requests()); n != 3 {
+		t.Errorf("upstream got %d requests, want one per paragraph", n)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, postJSON("/translate/stream", `{"text":"\n\n","target_lang":"DE"}`))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("blank text: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 16:52:03
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 10:12:44
+ * @FilePath: /DeepLX/service/subtitle.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/document"
+)
+
+type PayloadSubtitle struct {
+	TransText     string          `json:"text"`
+	Cues          []*document.Cue `json:"cues"`
+	Format        string          `json:"format"`
+	SourceLang    string          `json:"source_lang"`
+	TargetLang    string          `json:"target_lang"`
+	MaxLineLength *int            `json:"max_line_length"`
+}
+
+// subtitleHandler translates SRT or WebVTT subtitles cue by cue. The
+// subtitle is either posted as raw file body with the options in the query,
+// or as JSON holding the raw file in "text" or a cue list in "cues".
+// Raw bodies get a raw file back unless output=json is set.
+func subtitleHandler(live *LiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		req := PayloadSubtitle{}
+		raw := c.ContentType() != gin.MIMEJSON
+		if raw {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    http.StatusBadRequest,
+					"message": "Invalid request payload",
+				})
+				return
+			}
+			req.TransText = string(body)
+			req.SourceLang = c.Query("source_lang")
+			req.TargetLang = c.Query("target_lang")
+			if value := c.Query("max_line_length"); value != "" {
+				length, err := strconv.Atoi(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{
+						"code":    http.StatusBadRequest,
+						"message": "Invalid max_line_length value",
+					})
+					return
+				}
+				req.MaxLineLength = &length
+			}
+		} else if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid request payload",
+			})
+			return
+		}
+
+		if req.TargetLang == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "target_lang is required",
+			})
+			return
+		}
+
+		var subtitle *document.Subtitle
+		if len(req.Cues) > 0 {
+			if err := document.ValidateCues(req.Cues); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    http.StatusBadRequest,
+					"message": "Invalid cues: " + err.Error(),
+				})
+				return
+			}
+			format := req.Format
+			if format != "vtt" {
+				format = "srt"
+			}
+			subtitle = document.NewSubtitle(format, req.Cues)
+		} else {
+			var err error
+			subtitle, err = document.ParseSubtitle([]byte(req.TransText))
+			if errors.Is(err, document.ErrEmptySubtitle) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"code":    http.StatusNotFound,
+					"message": "No subtitle to translate",
+				})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    http.StatusBadRequest,
+					"message": "Invalid subtitle: " + err.Error(),
+				})
+				return
+			}
+		}
+
+		maxLineLength := cfg.SubtitleLineLength
+		if req.MaxLineLength != nil {
+			maxLineLength = *req.MaxLineLength
+		}
+
+		texts := make([]string, len(subtitle.Cues()))
+		for i, cue := range subtitle.Cues() {
+			texts[i] = cue.Text
+		}
+		outcome := &batchOutcome{}
+		translateFunc := batchTranslator(c.Request.Context(), requestTranslator(c, cfg), cfg, req.SourceLang, req.TargetLang, outcome)
+
+		start := time.Now()
+		err := document.TranslateCues(subtitle.Cues(), translateFunc, maxLineLength)
+		outcome.log(c, cfg, req.SourceLang, req.TargetLang, strings.Join(texts, "\n"), err, time.Since(start))
+		if err != nil {
+			var te *translationError
+			if errors.As(err, &te) {
+				c.JSON(te.result.Code, gin.H{
+					"code":    te.result.Code,
+					"message": te.result.Message,
+				})
+				return
+			}
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    http.StatusServiceUnavailable,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		sourceLang := req.SourceLang
+		if outcome.result.SourceLang != "" {
+			sourceLang = outcome.result.SourceLang
+		}
+		if raw && c.Query("output") != "json" {
+			contentType := "application/x-subrip"
+			if subtitle.Format == "vtt" {
+				contentType = "text/vtt"
+			}
+			c.Data(http.StatusOK, contentType+"; charset=utf-8", subtitle.Bytes())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"code":        http.StatusOK,
+			"format":      subtitle.Format,
+			"cues":        subtitle.Cues(),
+			"data":        string(subtitle.Bytes()),
+			"source_lang": sourceLang,
+			"target_lang": req.TargetLang,
+		})
+	}
+}
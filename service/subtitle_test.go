@@ -0,0 +1,60 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 15:51:44
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 15:51:44
+ * @FilePath: /DeepLX/service/subtitle_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubtitle(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nHello.\n"
+	req := httptest.NewRequest(http.MethodPost, "/translate/subtitle?source_lang=EN&target_lang=DE", strings.NewReader(srt))
+	req.Header.Set("Content-Type", "application/x-subrip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "1\n00:00:01,000 --> 00:00:02,000\n[DE] Hello.\n" {
+		t.Errorf("raw SRT: status = %d, body %q", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"empty", `{"text":"  ","target_lang":"DE"}`, http.StatusNotFound, "No subtitle to translate"},
+		{"malformed", `{"text":"1\n00:00:01 --> later\nHello.","target_lang":"DE"}`, http.StatusBadRequest, "Invalid subtitle: invalid timing line"},
+		{"no cues", `{"text":"Hello.","target_lang":"DE"}`, http.StatusBadRequest, "Invalid subtitle: no cue found"},
+		{"null cue", `{"cues":[null],"target_lang":"DE"}`, http.StatusBadRequest, "Invalid cues: cue 1 is missing"},
+		{"invalid cue timestamps", `{"cues":[{"start":"1s","end":"00:00:02,000","text":"Hi"}],"target_lang":"DE"}`, http.StatusBadRequest, "Invalid cues: cue 1 has invalid timestamps"},
+	}
+	for _, tt := range tests {
+		w, body := serve(t, router, postJSON("/translate/subtitle", tt.body))
+		message, _ := body["message"].(string)
+		if w.Code != tt.status || !strings.HasPrefix(message, tt.message) {
+			t.Errorf("%s: status = %d, message %q, want %d %q", tt.name, w.Code, message, tt.status, tt.message)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/translate/subtitle?source_lang=EN&target_lang=DE", strings.NewReader(srt))
+	req.Header.Set("Content-Type", "application/x-subrip")
+	entry := lastLogEntry(t, router, req)
+	if entry["source_lang"] != "EN" || entry["target_lang"] != "DE" || entry["characters"] != 6.0 || entry["outcome"] != "ok" {
+		t.Errorf("log entry = %v, want the translation logged", entry)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 10:58:03
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 10:58:03
+ * @FilePath: /DeepLX/service/tracing.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the service package
+var tracer = otel.Tracer("github.com/OwO-Network/DeepLX/service")
+
+// setupTracing installs the tracer provider exporting to the exporter of
+// cfg, "otlp" (OTLP over HTTP) or "stdout", and the W3C trace context
+// propagator. It returns the function flushing and stopping the provider,
+// or nil when tracing is disabled. The OTLP exporter also honours the
+// standard OTEL_EXPORTER_OTLP_* environment variables.
+func setupTracing(cfg *Config) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.TraceExporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.TraceEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.TraceEndpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		err = fmt.Errorf("unknown trace exporter %q", cfg.TraceExporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TraceSampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "deeplx"))),
+	)
+	otel.SetTracerProvider(provider)
+	slog.Info("Tracing enabled", "exporter", cfg.TraceExporter)
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware continues the trace of the incoming request, if any, in
+// a server span around the handler and adds its trace ID to the access log
+func tracingMiddleware(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		),
+	)
+	defer span.End()
+
+	if span.SpanContext().IsValid() {
+		annotate(c, slog.String("trace_id", span.SpanContext().TraceID().String()))
+	}
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 11:02:19
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 20:31:05
+ * @FilePath: /DeepLX/service/write.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/OwO-Network/DeepLX/translate"
+)
+
+// PayloadWrite is a /v2/write/rephrase request, as JSON or form
+type PayloadWrite struct {
+	Text         []string `json:"text" form:"text"`
+	TargetLang   string   `json:"target_lang" form:"target_lang"`
+	WritingStyle string   `json:"writing_style" form:"writing_style"`
+	Tone         string   `json:"tone" form:"tone"`
+}
+
+// writeHandler improves texts with DeepL Write, answering like the
+// /v2/write/rephrase endpoint of the official API. Every improvement has the
+// other rephrasings of DeepL as alternatives.
+func writeHandler(live *LiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := live.Get()
+		req := PayloadWrite{}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "Invalid request payload",
+			})
+			return
+		}
+		if strings.TrimSpace(strings.Join(req.Text, "")) == "" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    http.StatusNotFound,
+				"message": "No text to improve",
+			})
+			return
+		}
+
+		proxyURL := cfg.NextProxy()
+		start := time.Now()
+		improvements, err := translate.Rephrase(c.Request.Context(), req.Text, req.TargetLang, req.WritingStyle, req.Tone, proxyURL, "")
+		logTranslation(c, cfg, "", req.TargetLang, strings.Join(req.Text, "\n"), rephraseResult(improvements, err), proxyURL, time.Since(start))
+		if err != nil {
+			status := translate.ErrorStatus(err)
+			c.Error(err)
+			c.JSON(status, gin.H{
+				"code":    status,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"improvements": improvements,
+		})
+	}
+}
+
+// rephraseResult returns the outcome of a rephrasing as translation result
+// for logTranslation
+func rephraseResult(improvements []translate.Improvement, err error) translate.DeepLXTranslationResult {
+	if err != nil {
+		return translate.DeepLXTranslationResult{
+			Code:    translate.ErrorStatus(err),
+			Message: err.Error(),
+		}
+	}
+	result := translate.DeepLXTranslationResult{Code: http.StatusOK}
+	texts := make([]string, len(improvements))
+	for i, improvement := range improvements {
+		texts[i] = improvement.Text
+	}
+	result.Data = strings.Join(texts, "\n")
+	if len(improvements) > 0 {
+		result.SourceLang = improvements[0].DetectedSourceLang
+		result.TargetLang = improvements[0].TargetLang
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 11:40:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 11:40:52
+ * @FilePath: /DeepLX/service/write_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// writeResponse is the body of a /v2/write/rephrase response
+type writeResponse struct {
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	Improvements []struct {
+		Text               string   `json:"text"`
+		Alternatives       []string `json:"alternatives"`
+		DetectedSourceLang string   `json:"detected_source_language"`
+		TargetLang         string   `json:"target_language"`
+	} `json:"improvements"`
+}
+
+func rephrase(t *testing.T, router http.Handler, req *http.Request) (int, writeResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response writeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON response %q", w.Body)
+	}
+	return w.Code, response
+}
+
+func TestWrite(t *testing.T) {
+	router, upstream := newTestRouter(t)
+
+	status, response := rephrase(t, router, postJSON("/v2/write/rephrase", `{"text":["Hello, how are you doing today? The weather is lovely.","Good bye"],"target_lang":"en-US","writing_style":"business"}`))
+	if status != http.StatusOK || len(response.Improvements) != 2 {
+		t.Fatalf("status = %d, response %+v", status, response)
+	}
+	got := response.Improvements[0]
+	if got.Text != "[EN-US] Hello, how are you doing today? The weather is lovely." || got.DetectedSourceLang != "EN" || got.TargetLang != "EN-US" {
+		t.Errorf("improvement = %+v", got)
+	}
+	if len(got.Alternatives) != 3 || got.Alternatives[0] != got.Text+" (1)" {
+		t.Errorf("alternatives = %q, want the other 3 beams", got.Alternatives)
+	}
+
+	requests := upstream.Requests()
+	if len(requests) != 1 || requests[0].Method != "LMT_handle_jobs" || requests[0].TargetLang != "EN" || len(requests[0].Texts) != 2 {
+		t.Fatalf("upstream requests = %+v, want a single LMT_handle_jobs request", requests)
+	}
+	for _, want := range []string{`"mode":"write"`, `"regionalVariant":"en-US"`, `"writingStyle":"business"`} {
+		if !strings.Contains(string(requests[0].Body), want) {
+			t.Errorf("upstream body %s does not contain %s", requests[0].Body, want)
+		}
+	}
+
+	entry := lastLogEntry(t, router, postJSON("/v2/write/rephrase", `{"text":["Please send me the report by Friday."],"target_lang":"de"}`))
+	if entry["source_lang"] != "EN" || entry["target_lang"] != "DE" || entry["characters"] != 36.0 || entry["egress"] != "direct" || entry["outcome"] != "ok" {
+		t.Errorf("log entry = %v, want the rephrasing logged", entry)
+	}
+
+	// Forms are accepted, and the target language defaults to the detected one
+	form := url.Values{"text": {"Das ist ein ganz normaler deutscher Satz."}, "tone": {"friendly"}}
+	req := httptest.NewRequest(http.MethodPost, "/v2/write/rephrase", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if status, response = rephrase(t, router, req); status != http.StatusOK || response.Improvements[0].TargetLang != "DE" {
+		t.Errorf("form: status = %d, response %+v", status, response)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"style and tone", `{"text":["Hello"],"target_lang":"EN","writing_style":"casual","tone":"friendly"}`, http.StatusBadRequest},
+		{"unknown tone", `{"text":["Hello"],"target_lang":"EN","tone":"angry"}`, http.StatusBadRequest},
+		{"unsupported language", `{"text":["Hello"],"target_lang":"JA"}`, http.StatusBadRequest},
+		{"blank texts", `{"text":[" "],"target_lang":"EN"}`, http.StatusNotFound},
+	} {
+		if status, response = rephrase(t, router, postJSON("/v2/write/rephrase", tt.body)); status != tt.status {
+			t.Errorf("%s: status = %d, want %d, response %+v", tt.name, status, tt.status, response)
+		}
+	}
+
+	upstream.RateLimit(1)
+	if status, _ = rephrase(t, router, postJSON("/v2/write/rephrase", `{"text":["Hello"],"target_lang":"EN"}`)); status != http.StatusTooManyRequests {
+		t.Errorf("rate limited: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
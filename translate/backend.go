@@ -0,0 +1,217 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 18:05:31
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-23 11:25:50
+ * @FilePath: /DeepLX/translate/backend.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/imroc/req/v3"
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Request is a translation request passed to a Translator
+type Request struct {
+	SourceLang string
+	// SourceLangHints are the likely source languages when SourceLang is
+	// auto, as DeepL language codes
+	SourceLangHints []string
+	TargetLang      string
+	Text            string
+	TagHandling     string
+	// ProxyURL and DlSession are used by the backend reaching DeepL's web
+	// endpoint and ignored by the others
+	ProxyURL  string
+	DlSession string
+}
+
+// Translator is a translation backend
+type Translator interface {
+	// Name identifies the backend, it is reported as method of results
+	// unless the backend reports a method of its own
+	Name() string
+	// Translate translates req.Text. Like TranslateByDeepLX, failures
+	// reported by the backend are returned as result with an error code.
+	Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error)
+}
+
+// BatchTranslator is a Translator translating several texts in one go
+type BatchTranslator interface {
+	Translator
+	// TranslateTexts translates texts, returning the translations in Texts
+	TranslateTexts(ctx context.Context, req Request, texts []string) (DeepLXTranslationResult, error)
+}
+
+// TranslateAll translates texts with t, as a batch when t is a
+// BatchTranslator and text by text otherwise
+func TranslateAll(ctx context.Context, t Translator, req Request, texts []string) (DeepLXTranslationResult, error) {
+	if bt, ok := t.(BatchTranslator); ok {
+		return bt.TranslateTexts(ctx, req, texts)
+	}
+	return translateEach(ctx, t, req, texts)
+}
+
+// translateEach translates texts one request at a time
+func translateEach(ctx context.Context, t Translator, req Request, texts []string) (DeepLXTranslationResult, error) {
+	if len(texts) == 0 {
+		return DeepLXTranslationResult{
+			Code:    http.StatusNotFound,
+			Message: "No text to translate",
+		}, nil
+	}
+
+	var result DeepLXTranslationResult
+	translations := make([]string, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			translations[i] = text
+			continue
+		}
+		textReq := req
+		textReq.Text = text
+		r, err := t.Translate(ctx, textReq)
+		if err != nil || r.Code != http.StatusOK {
+			return r, err
+		}
+		translations[i] = r.Data
+		if result.Code == 0 {
+			result = r
+			// Keep the language of the first text for the following ones
+			req.SourceLang = r.SourceLang
+		}
+	}
+	result.Code = http.StatusOK
+	result.Data = strings.Join(translations, "\n")
+	result.Texts = translations
+	result.Alternatives = nil
+	result.TargetLang = req.TargetLang
+	return result, nil
+}
+
+// deeplxTranslator translates through DeepL's web JSON-RPC endpoint. A
+// named one is a configured backend, whose results carry its name as
+// method in place of "Free" or "Pro".
+type deeplxTranslator struct {
+	name string
+}
+
+// NewDeepLX returns the Translator of DeepL's web JSON-RPC endpoint, the
+// TranslateByDeepLX path
+func NewDeepLX() BatchTranslator {
+	return deeplxTranslator{}
+}
+
+// NewNamedDeepLX returns the Translator of DeepL's web JSON-RPC endpoint
+// for the configured backend name
+func NewNamedDeepLX(name string) BatchTranslator {
+	return deeplxTranslator{name: name}
+}
+
+func (t deeplxTranslator) Name() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "deeplx"
+}
+
+// named sets the method of a successful result to the backend name
+func (t deeplxTranslator) named(result DeepLXTranslationResult, err error) (DeepLXTranslationResult, error) {
+	if t.name != "" && err == nil && result.Code == http.StatusOK {
+		result.Method = t.name
+	}
+	return result, err
+}
+
+func (t deeplxTranslator) Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error) {
+	return t.named(TranslateByDeepLX(ctx, req.SourceLang, req.SourceLangHints, req.TargetLang, req.Text, req.TagHandling, req.ProxyURL, req.DlSession))
+}
+
+func (t deeplxTranslator) TranslateTexts(ctx context.Context, req Request, texts []string) (DeepLXTranslationResult, error) {
+	// Batches are sent as plain text, tag handling needs a request per text
+	if req.TagHandling != "" {
+		return translateEach(ctx, t, req, texts)
+	}
+	return t.named(TranslateTexts(ctx, req.SourceLang, req.SourceLangHints, req.TargetLang, texts, req.TagHandling, req.ProxyURL, req.DlSession))
+}
+
+// isAuto reports whether lang asks for the source language to be detected
+func isAuto(lang string) bool {
+	return lang == "" || strings.EqualFold(lang, "auto")
+}
+
+// postBackend posts payload as JSON to a backend and returns the parsed
+// response. Error statuses are returned as *UpstreamError of the backend,
+// with the message taken from the response where possible.
+func postBackend(ctx context.Context, backend, url string, headers map[string]string, payload any) (gjson.Result, error) {
+	ctx, span := tracer.Start(ctx, "backend."+backend, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(attribute.String("url.full", url))
+
+	resp, err := req.C().R().
+		SetContext(ctx).
+		SetHeaders(headers).
+		SetBodyJsonMarshal(payload).
+		Post(url)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return gjson.Result{}, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	body := resp.Bytes()
+	if resp.StatusCode != http.StatusOK {
+		span.SetStatus(codes.Error, resp.Status)
+		return gjson.Result{}, backendError(backend, resp.StatusCode, body)
+	}
+	if !gjson.ValidBytes(body) {
+		span.SetStatus(codes.Error, ErrInvalidResponse.Error())
+		return gjson.Result{}, fmt.Errorf("%s: %w", backend, ErrInvalidResponse)
+	}
+	return gjson.ParseBytes(body), nil
+}
+
+// backendError returns the error of a backend response with an error status
+func backendError(backend string, status int, body []byte) *UpstreamError {
+	e := &UpstreamError{
+		Backend: backend,
+		Code:    int64(status),
+		Message: http.StatusText(status),
+	}
+	if gjson.ValidBytes(body) {
+		response := gjson.ParseBytes(body)
+		for _, path := range []string{"error.message", "message", "error", "detail"} {
+			if message := response.Get(path); message.Type == gjson.String && message.String() != "" {
+				e.Message = message.String()
+				break
+			}
+		}
+	} else if len(body) > 0 {
+		e.Message = bodySnippet(body, maxSnippetLength)
+	}
+
+	switch status {
+	case http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case StatusQuotaExceeded, http.StatusPaymentRequired:
+		e.Kind = ErrQuotaExceeded
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		e.Kind = ErrInvalidRequest
+	}
+	return e
+}
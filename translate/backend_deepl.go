@@ -0,0 +1,126 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 18:27:14
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 18:27:14
+ * @FilePath: /DeepLX/translate/backend_deepl.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DeepLAPIURL is the endpoint of the official DeepL API
+	DeepLAPIURL = "https://api.deepl.com"
+	// DeepLFreeAPIURL is the endpoint of the official DeepL API for free
+	// accounts, whose auth keys end in ":fx"
+	DeepLFreeAPIURL = "https://api-free.deepl.com"
+)
+
+// deeplAPITranslator translates through the official DeepL API
+type deeplAPITranslator struct {
+	name    string
+	url     string
+	authKey string
+}
+
+// OfficialAPIURL returns the endpoint of the official DeepL API serving
+// authKey
+func OfficialAPIURL(authKey string) string {
+	if strings.HasSuffix(authKey, ":fx") {
+		return DeepLFreeAPIURL
+	}
+	return DeepLAPIURL
+}
+
+// NewDeepLAPI returns a Translator of the official DeepL API using authKey.
+// The endpoint is picked from the key when url is empty.
+func NewDeepLAPI(name, url, authKey string) BatchTranslator {
+	if url == "" {
+		url = OfficialAPIURL(authKey)
+	}
+	return &deeplAPITranslator{
+		name:    name,
+		url:     strings.TrimSuffix(url, "/"),
+		authKey: authKey,
+	}
+}
+
+func (t *deeplAPITranslator) Name() string {
+	return t.name
+}
+
+func (t *deeplAPITranslator) Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error) {
+	result, err := t.TranslateTexts(ctx, req, []string{req.Text})
+	result.Texts = nil
+	return result, err
+}
+
+func (t *deeplAPITranslator) TranslateTexts(ctx context.Context, req Request, texts []string) (DeepLXTranslationResult, error) {
+	if len(texts) == 0 || (len(texts) == 1 && texts[0] == "") {
+		return DeepLXTranslationResult{
+			Code:    http.StatusNotFound,
+			Message: "No text to translate",
+		}, nil
+	}
+
+	payload := map[string]any{
+		"text":        texts,
+		"target_lang": strings.ToUpper(req.TargetLang),
+	}
+	if !isAuto(req.SourceLang) {
+		payload["source_lang"] = strings.ToUpper(req.SourceLang)
+	}
+	// Markdown is not supported by the API and sent as plain text
+	if req.TagHandling == "html" || req.TagHandling == "xml" {
+		payload["tag_handling"] = req.TagHandling
+	}
+
+	response, err := postBackend(ctx, t.name, t.url+"/v2/translate", map[string]string{
+		"Authorization": "DeepL-Auth-Key " + t.authKey,
+	}, payload)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	translations := response.Get("translations").Array()
+	if len(translations) != len(texts) {
+		return DeepLXTranslationResult{
+			Code:    http.StatusServiceUnavailable,
+			Message: fmt.Sprintf("%s: expected %d translations, got %d", t.name, len(texts), len(translations)),
+		}, nil
+	}
+
+	out := make([]string, len(translations))
+	characters := 0
+	for i, translation := range translations {
+		out[i] = translation.Get("text").String()
+		characters += utf8.RuneCountInString(texts[i])
+	}
+	sourceLang := translations[0].Get("detected_source_language").String()
+	if sourceLang == "" {
+		sourceLang = strings.ToUpper(req.SourceLang)
+	}
+	countCharacters(sourceLang, req.TargetLang, characters)
+
+	return DeepLXTranslationResult{
+		Code:       http.StatusOK,
+		ID:         getRandomNumber(),
+		Data:       strings.Join(out, "\n"),
+		Texts:      out,
+		SourceLang: sourceLang,
+		TargetLang: req.TargetLang,
+		Method:     t.name,
+	}, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 18:49:02
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 18:49:02
+ * @FilePath: /DeepLX/translate/backend_libre.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// libreTranslator translates through a LibreTranslate compatible API
+type libreTranslator struct {
+	name   string
+	url    string
+	apiKey string
+}
+
+// NewLibreTranslate returns a Translator of the LibreTranslate compatible
+// API at url, apiKey is sent when not empty
+func NewLibreTranslate(name, url, apiKey string) Translator {
+	return &libreTranslator{
+		name:   name,
+		url:    strings.TrimSuffix(url, "/"),
+		apiKey: apiKey,
+	}
+}
+
+func (t *libreTranslator) Name() string {
+	return t.name
+}
+
+// libreLang returns the LibreTranslate code of a DeepL language code, like
+// "pt" for "PT-BR"
+func libreLang(lang string) string {
+	lang, _, _ = strings.Cut(lang, "-")
+	return strings.ToLower(lang)
+}
+
+func (t *libreTranslator) Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error) {
+	if req.Text == "" {
+		return DeepLXTranslationResult{
+			Code:    http.StatusNotFound,
+			Message: "No text to translate",
+		}, nil
+	}
+
+	payload := map[string]any{
+		"q":            req.Text,
+		"source":       "auto",
+		"target":       libreLang(req.TargetLang),
+		"format":       "text",
+		"alternatives": 3,
+	}
+	if !isAuto(req.SourceLang) {
+		payload["source"] = libreLang(req.SourceLang)
+	}
+	if req.TagHandling == "html" || req.TagHandling == "xml" {
+		payload["format"] = "html"
+	}
+	if t.apiKey != "" {
+		payload["api_key"] = t.apiKey
+	}
+
+	response, err := postBackend(ctx, t.name, t.url+"/translate", nil, payload)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	data := response.Get("translatedText").String()
+	if data == "" {
+		return DeepLXTranslationResult{
+			Code:    http.StatusServiceUnavailable,
+			Message: "Translation failed",
+		}, nil
+	}
+	var alternatives []string
+	for _, alternative := range response.Get("alternatives").Array() {
+		if text := alternative.String(); text != "" {
+			alternatives = append(alternatives, text)
+		}
+	}
+	sourceLang := strings.ToUpper(response.Get("detectedLanguage.language").String())
+	if sourceLang == "" {
+		sourceLang = strings.ToUpper(req.SourceLang)
+	}
+	countCharacters(sourceLang, req.TargetLang, utf8.RuneCountInString(req.Text))
+
+	return DeepLXTranslationResult{
+		Code:         http.StatusOK,
+		ID:           getRandomNumber(),
+		Data:         data,
+		Alternatives: alternatives,
+		SourceLang:   sourceLang,
+		TargetLang:   req.TargetLang,
+		Method:       t.name,
+	}, nil
+}
@@ -0,0 +1,128 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 19:08:45
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 19:08:45
+ * @FilePath: /DeepLX/translate/backend_openai.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// OpenAIURL is the endpoint of the OpenAI API
+const OpenAIURL = "https://api.openai.com/v1"
+
+// openaiTranslator translates by prompting an OpenAI compatible
+// chat completions API
+type openaiTranslator struct {
+	name   string
+	url    string
+	apiKey string
+	model  string
+}
+
+// NewOpenAI returns a Translator prompting model through the OpenAI
+// compatible chat completions API at url, OpenAIURL when empty
+func NewOpenAI(name, url, apiKey, model string) Translator {
+	if url == "" {
+		url = OpenAIURL
+	}
+	return &openaiTranslator{
+		name:   name,
+		url:    strings.TrimSuffix(url, "/"),
+		apiKey: apiKey,
+		model:  model,
+	}
+}
+
+func (t *openaiTranslator) Name() string {
+	return t.name
+}
+
+// markupNames names the markup of a tag_handling value in prompts
+var markupNames = map[string]string{
+	"html":     "HTML",
+	"xml":      "XML",
+	"markdown": "Markdown",
+}
+
+func (t *openaiTranslator) Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error) {
+	if req.Text == "" {
+		return DeepLXTranslationResult{
+			Code:    http.StatusNotFound,
+			Message: "No text to translate",
+		}, nil
+	}
+
+	// Chat models are not limited to the languages of DeepL, and detect the
+	// language themselves when unsure
+	sourceLang := strings.ToUpper(req.SourceLang)
+	if isAuto(sourceLang) {
+		sourceLang = ""
+		if detection := DetectLanguage(req.Text, req.SourceLangHints); detection.Confidence >= getDetectConfidence() {
+			sourceLang = detection.Lang
+		}
+	}
+
+	prompt := fmt.Sprintf("You are a translation engine. Translate the text of the user to the language with the code %s.", req.TargetLang)
+	if sourceLang != "" {
+		prompt = fmt.Sprintf("You are a translation engine. Translate the text of the user from the language with the code %s to the language with the code %s.", sourceLang, req.TargetLang)
+	}
+	if markup, ok := markupNames[req.TagHandling]; ok {
+		prompt += fmt.Sprintf(" The text is %s, keep its markup unchanged.", markup)
+	}
+	prompt += " Reply with the translation only, without explanations or quotes."
+
+	payload := map[string]any{
+		"model": t.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt},
+			{"role": "user", "content": req.Text},
+		},
+		"temperature": 0,
+	}
+	headers := map[string]string{}
+	if t.apiKey != "" {
+		headers["Authorization"] = "Bearer " + t.apiKey
+	}
+
+	response, err := postBackend(ctx, t.name, t.url+"/chat/completions", headers, payload)
+	if err != nil {
+		// Running out of credits is reported as 429 with its own code
+		var upstream *UpstreamError
+		if errors.As(err, &upstream) && strings.Contains(upstream.Message, "quota") {
+			upstream.Kind = ErrQuotaExceeded
+		}
+		return errorResult(err), nil
+	}
+
+	data := strings.TrimSpace(response.Get("choices.0.message.content").String())
+	if data == "" {
+		return DeepLXTranslationResult{
+			Code:    http.StatusServiceUnavailable,
+			Message: "Translation failed",
+		}, nil
+	}
+	countCharacters(sourceLang, req.TargetLang, utf8.RuneCountInString(req.Text))
+
+	return DeepLXTranslationResult{
+		Code:       http.StatusOK,
+		ID:         getRandomNumber(),
+		Data:       data,
+		SourceLang: sourceLang,
+		TargetLang: req.TargetLang,
+		Method:     t.name,
+	}, nil
+}
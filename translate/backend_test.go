@@ -0,0 +1,42 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 18:52:16
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 18:52:16
+ * @FilePath: /DeepLX/translate/backend_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTranslateAllTagHandling(t *testing.T) {
+	newFakeUpstream(t)
+	texts := []string{"# One", "Two `code`"}
+
+	result, err := TranslateAll(context.Background(), NewDeepLX(), Request{SourceLang: "EN", TargetLang: "DE"}, texts)
+	if err != nil || result.Code != http.StatusOK {
+		t.Fatalf("TranslateAll() = %+v, %v", result, err)
+	}
+	if want := []string{"[DE] # One", "[DE] Two `code`"}; !reflect.DeepEqual(result.Texts, want) {
+		t.Errorf("plain texts = %q, want %q", result.Texts, want)
+	}
+
+	// Tag handling applies to every text of the batch
+	result, err = TranslateAll(context.Background(), NewDeepLX(), Request{SourceLang: "EN", TargetLang: "DE", TagHandling: "markdown"}, texts)
+	if err != nil || result.Code != http.StatusOK {
+		t.Fatalf("TranslateAll() = %+v, %v", result, err)
+	}
+	if want := []string{"# [DE] One", "[DE] Two `code`"}; !reflect.DeepEqual(result.Texts, want) {
+		t.Errorf("markdown texts = %q, want %q", result.Texts, want)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 13:20:08
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 13:20:08
+ * @FilePath: /DeepLX/translate/batch.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// TranslateTexts translates several texts at once. Texts are packed into as
+// few upstream requests as the configured maximum text length allows, and
+// the translations are returned in Texts in the same order. sourceLangHints
+// are the likely source languages when sourceLang is auto.
+func TranslateTexts(ctx context.Context, sourceLang string, sourceLangHints []string, targetLang string, texts []string, tagHandling string, proxyURL string, dlSession string) (DeepLXTranslationResult, error) {
+	if len(texts) == 0 {
+		return DeepLXTranslationResult{
+			Code:    http.StatusNotFound,
+			Message: "No text to translate",
+		}, nil
+	}
+
+	// Get detected language if source language is auto
+	if sourceLang == "auto" || sourceLang == "" {
+		sourceLang = detectLang(ctx, strings.Join(texts, "\n"), sourceLangHints)
+	}
+
+	maxLength, _ := getChunking()
+	translations := make([]string, len(texts))
+	var id int64
+
+	var batch []int
+	batchLength := 0
+	flush := func() DeepLXTranslationResult {
+		if len(batch) == 0 {
+			return DeepLXTranslationResult{Code: http.StatusOK}
+		}
+		items := make([]TextItem, len(batch))
+		for i, index := range batch {
+			items[i] = TextItem{Text: texts[index]}
+		}
+		requestID, result, err := handleTexts(ctx, sourceLang, targetLang, items, proxyURL, dlSession)
+		if err != nil {
+			return errorResult(err)
+		}
+		textsArray := result.Get("result.texts").Array()
+		if len(textsArray) != len(batch) {
+			return DeepLXTranslationResult{
+				Code:    http.StatusServiceUnavailable,
+				Message: "Translation failed",
+			}
+		}
+		for i, index := range batch {
+			translations[index] = textsArray[i].Get("text").String()
+		}
+		if detectedLang := result.Get("result.lang").String(); detectedLang != "" {
+			sourceLang = detectedLang
+		}
+		if id == 0 {
+			id = requestID
+		}
+		batch = batch[:0]
+		batchLength = 0
+		return DeepLXTranslationResult{Code: http.StatusOK}
+	}
+
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			translations[i] = text
+			continue
+		}
+
+		length := utf8.RuneCountInString(text)
+		if length > maxLength {
+			// Oversized texts go through the chunking path on their own
+			result, err := TranslateByDeepLX(ctx, sourceLang, sourceLangHints, targetLang, text, tagHandling, proxyURL, dlSession)
+			if err != nil {
+				return DeepLXTranslationResult{}, err
+			}
+			if result.Code != http.StatusOK {
+				return result, nil
+			}
+			translations[i] = result.Data
+			continue
+		}
+
+		if batchLength+length > maxLength {
+			if result := flush(); result.Code != http.StatusOK {
+				return result, nil
+			}
+		}
+		batch = append(batch, i)
+		batchLength += length
+	}
+	if result := flush(); result.Code != http.StatusOK {
+		return result, nil
+	}
+
+	return DeepLXTranslationResult{
+		Code:       http.StatusOK,
+		ID:         id,
+		Data:       strings.Join(translations, "\n"),
+		Texts:      translations,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Method:     map[bool]string{true: "Pro", false: "Free"}[dlSession != ""],
+	}, nil
+}
@@ -0,0 +1,267 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 11:02:15
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 00:24:51
+ * @FilePath: /DeepLX/translate/chunk.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxTextLength is the default maximum number of characters sent in a single text item
+	DefaultMaxTextLength = 3000
+	// DefaultChunkConcurrency is the default number of chunks translated in parallel
+	DefaultChunkConcurrency = 1
+)
+
+var (
+	chunkMu          sync.RWMutex
+	maxTextLength    = DefaultMaxTextLength
+	chunkConcurrency = DefaultChunkConcurrency
+)
+
+// SetChunking configures the length above which texts are split into chunks
+// and how many chunks may be translated in parallel. Non-positive values
+// restore the defaults.
+func SetChunking(maxLength, concurrency int) {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxTextLength
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultChunkConcurrency
+	}
+	chunkMu.Lock()
+	maxTextLength = maxLength
+	chunkConcurrency = concurrency
+	chunkMu.Unlock()
+}
+
+// getChunking returns the current chunking settings
+func getChunking() (int, int) {
+	chunkMu.RLock()
+	defer chunkMu.RUnlock()
+	return maxTextLength, chunkConcurrency
+}
+
+var (
+	paragraphBoundary = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+	sentenceBoundary  = regexp.MustCompile(`[.!?。！？…]+["'”’)\]」』]*\s+|[。！？]+`)
+
+	// markupBoundary also ends sentences after tags, e.g. of block elements
+	markupBoundary = regexp.MustCompile(sentenceBoundary.String() + `|>\s*`)
+	markupTag      = regexp.MustCompile(`<[^<>]*>`)
+	voidElement    = regexp.MustCompile(`(?i)^<(?:area|base|br|col|embed|hr|img|input|link|meta|param|source|track|wbr)\b`)
+)
+
+// markupCuts returns for every byte offset of text, and its end, whether
+// text can be cut there without splitting a tag or an element. With
+// tagHandling "html", void elements like br need no closing tag.
+func markupCuts(text, tagHandling string) []bool {
+	cuts := make([]bool, len(text)+1)
+	depth, last := 0, 0
+	for _, loc := range markupTag.FindAllStringIndex(text, -1) {
+		for i := last; i <= loc[0]; i++ {
+			cuts[i] = depth == 0
+		}
+		tag := text[loc[0]:loc[1]]
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			if depth > 0 {
+				depth--
+			}
+		case strings.HasPrefix(tag, "<!"), strings.HasPrefix(tag, "<?"), strings.HasSuffix(tag, "/>"):
+		case tagHandling == "html" && voidElement.MatchString(tag):
+		default:
+			depth++
+		}
+		last = loc[1]
+	}
+	for i := last; i <= len(text); i++ {
+		cuts[i] = depth == 0
+	}
+	return cuts
+}
+
+// splitAfter splits s, found at offset in the text, after every match of re
+// where cuts allows it, so that concatenating the result gives back s
+// unchanged. A nil cuts allows every cut.
+func splitAfter(s string, offset int, re *regexp.Regexp, cuts []bool) []string {
+	var parts []string
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		if loc[1] == last || loc[1] == len(s) || (cuts != nil && !cuts[offset+loc[1]]) {
+			continue
+		}
+		parts = append(parts, s[last:loc[1]])
+		last = loc[1]
+	}
+	return append(parts, s[last:])
+}
+
+// hardSplit splits s into pieces of at most maxLen characters, preferring to
+// cut after whitespace
+func hardSplit(s string, maxLen int) []string {
+	var parts []string
+	for utf8.RuneCountInString(s) > maxLen {
+		cut, n, lastSpace := 0, 0, 0
+		for i, r := range s {
+			if n == maxLen {
+				cut = i
+				break
+			}
+			if unicode.IsSpace(r) {
+				lastSpace = i + utf8.RuneLen(r)
+			}
+			n++
+		}
+		if lastSpace > 0 {
+			cut = lastSpace
+		}
+		parts = append(parts, s[:cut])
+		s = s[cut:]
+	}
+	return append(parts, s)
+}
+
+// splitPieces breaks text into paragraphs, then sentences, then hard cuts,
+// until every piece fits into maxLen characters. With cuts, text is also
+// split after tags but only where cuts allows it, and hard cuts are left
+// out, so pieces of markup may exceed maxLen.
+func splitPieces(text string, maxLen int, cuts []bool) []string {
+	boundary := sentenceBoundary
+	if cuts != nil {
+		boundary = markupBoundary
+	}
+	var pieces []string
+	offset := 0
+	for _, paragraph := range splitAfter(text, 0, paragraphBoundary, cuts) {
+		if utf8.RuneCountInString(paragraph) <= maxLen {
+			pieces = append(pieces, paragraph)
+			offset += len(paragraph)
+			continue
+		}
+		for _, sentence := range splitAfter(paragraph, offset, boundary, cuts) {
+			if utf8.RuneCountInString(sentence) <= maxLen || cuts != nil {
+				pieces = append(pieces, sentence)
+				continue
+			}
+			pieces = append(pieces, hardSplit(sentence, maxLen)...)
+		}
+		offset += len(paragraph)
+	}
+	return pieces
+}
+
+// splitText splits text into chunks of at most maxLen characters on
+// paragraph, then sentence, boundaries. Concatenating the chunks gives back
+// the original text, whitespace and newlines included. With tagHandling
+// "html" or "xml" text is only split between top-level elements, never
+// inside a tag or an element.
+func splitText(text string, maxLen int, tagHandling string) []string {
+	if utf8.RuneCountInString(text) <= maxLen {
+		return []string{text}
+	}
+
+	var cuts []bool
+	if tagHandling == "html" || tagHandling == "xml" {
+		cuts = markupCuts(text, tagHandling)
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+	for _, piece := range splitPieces(text, maxLen, cuts) {
+		pieceLen := utf8.RuneCountInString(piece)
+		if currentLen > 0 && currentLen+pieceLen > maxLen {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLen = 0
+		}
+		current.WriteString(piece)
+		currentLen += pieceLen
+	}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
+	}
+	return chunks
+}
+
+// trimSpaces splits s into its leading whitespace, content and trailing whitespace
+func trimSpaces(s string) (string, string, string) {
+	core := strings.TrimLeftFunc(s, unicode.IsSpace)
+	lead := s[:len(s)-len(core)]
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	return lead, trimmed, core[len(trimmed):]
+}
+
+// translateChunks translates every chunk separately and reassembles the
+// results into a single translation, keeping the whitespace between chunks.
+// The result has no alternatives, those of the chunks cannot be combined
+// into alternatives of the whole text.
+func translateChunks(ctx context.Context, sourceLang, targetLang string, chunks []string, tagHandling string, proxyURL string, dlSession string, concurrency int) (DeepLXTranslationResult, error) {
+	results := make([]DeepLXTranslationResult, len(chunks))
+	errs := make([]error, len(chunks))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		_, core, _ := trimSpaces(chunk)
+		if core == "" {
+			results[i] = DeepLXTranslationResult{Code: http.StatusOK}
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, core string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = translateText(ctx, sourceLang, targetLang, core, tagHandling, proxyURL, dlSession)
+		}(i, core)
+	}
+	wg.Wait()
+
+	var id int64
+	var data strings.Builder
+	for i, chunk := range chunks {
+		if errs[i] != nil {
+			return DeepLXTranslationResult{}, errs[i]
+		}
+		if results[i].Code != http.StatusOK {
+			return results[i], nil
+		}
+		if id == 0 {
+			id = results[i].ID
+		}
+		if results[i].SourceLang != "" {
+			sourceLang = results[i].SourceLang
+		}
+		lead, _, trail := trimSpaces(chunk)
+		data.WriteString(lead)
+		data.WriteString(results[i].Data)
+		data.WriteString(trail)
+	}
+
+	return DeepLXTranslationResult{
+		Code:       http.StatusOK,
+		ID:         id,
+		Data:       data.String(),
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Method:     map[bool]string{true: "Pro", false: "Free"}[dlSession != ""],
+	}, nil
+}
@@ -0,0 +1,129 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 14:36:52
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 14:36:52
+ * @FilePath: /DeepLX/translate/chunk_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{"short text", "Hello world.", 20, []string{"Hello world."}},
+		{"paragraphs", "One two.\n\nThree four.\n \nFive.", 14, []string{"One two.\n\n", "Three four.\n \n", "Five."}},
+		{"paragraphs packed together", "One.\n\nTwo.\n\nThree four five.", 12, []string{"One.\n\nTwo.\n\n", "Three four ", "five."}},
+		{"sentences", "First one. Second one! Third one?", 12, []string{"First one. ", "Second one! ", "Third one?"}},
+		{"CJK sentences", "你好。今天天气很好。我们走吧。", 7, []string{"你好。", "今天天气很好。", "我们走吧。"}},
+		{"oversized sentence cut after spaces", "aaa bbb ccc ddd", 9, []string{"aaa bbb ", "ccc ddd"}},
+		{"oversized word cut hard", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"runes counted", "ääääää", 4, []string{"ääää", "ää"}},
+	}
+	for _, tt := range tests {
+		got := splitText(tt.text, tt.maxLen, "")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: splitText(%q, %d) = %q, want %q", tt.name, tt.text, tt.maxLen, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTextMarkup(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		tagHandling string
+		maxLen      int
+		want        []string
+	}{
+		{"between elements", "<p>One two.</p><p>Three four.</p>", "html", 20, []string{"<p>One two.</p>", "<p>Three four.</p>"}},
+		{"not inside an element", "<p>First one. Second one.</p>", "html", 12, []string{"<p>First one. Second one.</p>"}},
+		{"not inside a tag", `<a title="x. y. z">Hi.</a> Bye.`, "html", 10, []string{`<a title="x. y. z">Hi.</a> `, "Bye."}},
+		{"void elements", "One.<br>Two. Three.", "html", 8, []string{"One.<br>", "Two. ", "Three."}},
+		{"xml elements nest", "<x>One.<br>Two.</br></x> Three.", "xml", 8, []string{"<x>One.<br>Two.</br></x> ", "Three."}},
+		{"paragraphs", "<p>One.</p>\n\n<p>Two.</p>", "xml", 12, []string{"<p>One.</p>\n\n", "<p>Two.</p>"}},
+	}
+	for _, tt := range tests {
+		got := splitText(tt.text, tt.maxLen, tt.tagHandling)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: splitText(%q, %d, %q) = %q, want %q", tt.name, tt.text, tt.maxLen, tt.tagHandling, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTextKeepsText(t *testing.T) {
+	text := strings.Repeat("A sentence with some words.  Another one!\n", 20) +
+		"\n\n\t" + strings.Repeat("x", 50) + " \r\n\r\n" +
+		strings.Repeat("Ünïcödé wörds hère. ", 10)
+	for _, maxLen := range []int{7, 20, 45, 100} {
+		chunks := splitText(text, maxLen, "")
+		if got := strings.Join(chunks, ""); got != text {
+			t.Errorf("maxLen %d: chunks do not add up to the text, got %q", maxLen, got)
+		}
+		for _, chunk := range chunks {
+			if n := utf8.RuneCountInString(chunk); n > maxLen || n == 0 {
+				t.Errorf("maxLen %d: chunk %q has %d characters", maxLen, chunk, n)
+			}
+		}
+	}
+}
+
+func TestTranslateChunks(t *testing.T) {
+	upstream := newFakeUpstream(t)
+
+	chunks := []string{"\n  Hello. ", "\n\n", "World.\n"}
+	result, err := translateChunks(context.Background(), "EN", "DE", chunks, "", "", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Code != http.StatusOK || result.Data != "\n  [DE] Hello. \n\n[DE] World.\n" {
+		t.Errorf("translateChunks() = %+v, want the translations with the original whitespace", result)
+	}
+	if result.Alternatives != nil {
+		t.Errorf("alternatives = %q, want none for chunked texts", result.Alternatives)
+	}
+	if n := len(upstream.Requests()); n != 2 {
+		t.Errorf("upstream got %d requests, want one per non-blank chunk", n)
+	}
+
+	// A failed chunk fails the whole text
+	upstream.RateLimit(1)
+	result, err = translateChunks(context.Background(), "EN", "DE", chunks, "", "", "", 1)
+	if err != nil || result.Code != http.StatusTooManyRequests {
+		t.Errorf("rate limited chunk: translateChunks() = %+v, %v", result, err)
+	}
+}
+
+func TestTranslateByDeepLXChunked(t *testing.T) {
+	upstream := newFakeUpstream(t)
+	SetChunking(12, 2)
+	t.Cleanup(func() { SetChunking(0, 0) })
+
+	result, err := TranslateByDeepLX(context.Background(), "EN", nil, "DE", "First one. Second one!", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Data != "[DE] First one. [DE] Second one!" {
+		t.Errorf("Data = %q", result.Data)
+	}
+	if requests := upstream.Requests(); len(requests) != 2 {
+		t.Errorf("upstream got %d requests, want 2", len(requests))
+	}
+}
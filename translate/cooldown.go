@@ -0,0 +1,77 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 00:18:44
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 00:18:44
+ * @FilePath: /DeepLX/translate/cooldown.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is how long a proxy or dl-session is avoided after DeepL
+// answered 429 Too Many Requests through it
+const DefaultCooldown = time.Minute
+
+var (
+	cooldownMu     sync.Mutex
+	cooldownPeriod = DefaultCooldown
+	cooldownUntil  = map[string]time.Time{}
+)
+
+// SetCooldown configures how long a rate limited proxy or dl-session stays
+// in cooldown. A non-positive period restores the default.
+func SetCooldown(period time.Duration) {
+	if period <= 0 {
+		period = DefaultCooldown
+	}
+	cooldownMu.Lock()
+	cooldownPeriod = period
+	cooldownMu.Unlock()
+}
+
+// coolDown puts the proxy, direct access when proxyURL is empty, and the
+// dl-session of a rate limited request into cooldown
+func coolDown(proxyURL string, dlSession string) {
+	cooldownMu.Lock()
+	defer cooldownMu.Unlock()
+	until := time.Now().Add(cooldownPeriod)
+	cooldownUntil["proxy:"+proxyURL] = until
+	if dlSession != "" {
+		cooldownUntil["session:"+dlSession] = until
+	}
+}
+
+func inCooldown(key string) bool {
+	cooldownMu.Lock()
+	defer cooldownMu.Unlock()
+	until, ok := cooldownUntil[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(cooldownUntil, key)
+		return false
+	}
+	return true
+}
+
+// ProxyInCooldown reports whether DeepL recently rate limited requests
+// through proxyURL, or direct requests when proxyURL is empty
+func ProxyInCooldown(proxyURL string) bool {
+	return inCooldown("proxy:" + proxyURL)
+}
+
+// SessionInCooldown reports whether DeepL recently rate limited requests
+// with dlSession
+func SessionInCooldown(dlSession string) bool {
+	return inCooldown("session:" + dlSession)
+}
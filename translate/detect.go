@@ -0,0 +1,239 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 17:26:03
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 17:26:03
+ * @FilePath: /DeepLX/translate/detect.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/abadojack/whatlanggo"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/OwO-Network/DeepLX/metrics"
+)
+
+// AutoLang lets DeepL detect the source language
+const AutoLang = "auto"
+
+// DefaultDetectConfidence is the confidence below which the detected source
+// language is left to DeepL. whatlanggo rarely gets above 0.3 for a sentence,
+// 0.1 keeps most of them while leaving the guesses on a few words to DeepL.
+const DefaultDetectConfidence = 0.1
+
+var (
+	detectMu         sync.Mutex
+	detectConfidence = DefaultDetectConfidence
+)
+
+// SetDetectConfidence configures the confidence below which the detected
+// source language is left to DeepL. 0 always uses the local detection, 1
+// only languages detected with certainty.
+func SetDetectConfidence(confidence float64) {
+	detectMu.Lock()
+	detectConfidence = confidence
+	detectMu.Unlock()
+}
+
+func getDetectConfidence() float64 {
+	detectMu.Lock()
+	defer detectMu.Unlock()
+	return detectConfidence
+}
+
+// SourceLangs are the source languages supported by DeepL
+var SourceLangs = []string{
+	"AR", "BG", "CS", "DA", "DE", "EL", "EN", "ES", "ET", "FI", "FR", "HE",
+	"HU", "ID", "IT", "JA", "KO", "LT", "LV", "NB", "NL", "PL", "PT", "RO",
+	"RU", "SK", "SL", "SV", "TH", "TR", "UK", "VI", "ZH",
+}
+
+// langVariants are the regional target languages of DeepL
+var langVariants = []string{"EN-GB", "EN-US", "ES-419", "PT-BR", "PT-PT", "ZH-HANS", "ZH-HANT"}
+
+// metricsLang returns lang as metrics label, an upper-case DeepL language,
+// "auto" or "other", so that clients cannot grow the set of labels
+func metricsLang(lang string) string {
+	lang = strings.ToUpper(strings.TrimSpace(lang))
+	switch {
+	case lang == "" || lang == "AUTO":
+		return AutoLang
+	case slices.Contains(SourceLangs, lang), slices.Contains(langVariants, lang):
+		return lang
+	}
+	return "other"
+}
+
+// countCharacters records characters translated from sourceLang to
+// targetLang in the metrics
+func countCharacters(sourceLang, targetLang string, count int) {
+	metrics.CharactersTranslated(metricsLang(sourceLang), metricsLang(targetLang), count)
+}
+
+// langAliases maps detected languages onto the DeepL source language that
+// reads them
+var langAliases = map[string]string{
+	"NN": "NB",
+	"NO": "NB",
+}
+
+// whatlangLangs maps ISO 639-1 codes to the languages of whatlanggo
+var whatlangLangs = func() map[string]whatlanggo.Lang {
+	langs := make(map[string]whatlanggo.Lang, len(whatlanggo.Langs))
+	for lang := range whatlanggo.Langs {
+		if code := lang.Iso6391(); code != "" {
+			langs[code] = lang
+		}
+	}
+	return langs
+}()
+
+// Detection is the outcome of language detection
+type Detection struct {
+	// Lang is the detected language as upper case ISO 639-1 code, empty
+	// when the language is unknown
+	Lang string `json:"lang"`
+	// SourceLang is the DeepL source language of Lang, empty when DeepL
+	// does not support it
+	SourceLang string `json:"source_lang"`
+	// Confidence ranges from 0 to 1
+	Confidence float64 `json:"confidence"`
+	// Script is the Unicode script of the text, like "Latin"
+	Script string `json:"script"`
+}
+
+// DetectLanguage detects the language of text. hints are DeepL language
+// codes of the likely languages, the detection is restricted to them unless
+// none fits the text.
+func DetectLanguage(text string, hints []string) Detection {
+	info := whatlanggo.Detect(text)
+	if whitelist := hintedLangs(hints); len(whitelist) > 0 {
+		if hinted := whatlanggo.DetectWithOptions(text, whatlanggo.Options{Whitelist: whitelist}); hinted.Lang >= 0 {
+			info = hinted
+		}
+	}
+	return newDetection(info)
+}
+
+// DetectCandidates returns up to n likely languages of text, the one of
+// DetectLanguage first. The confidence of every candidate is relative to the
+// candidates that follow it. Scripts written in a single language, like
+// Hangul, have one candidate only.
+func DetectCandidates(text string, hints []string, n int) []Detection {
+	options := whatlanggo.Options{Blacklist: map[whatlanggo.Lang]bool{}}
+	if whitelist := hintedLangs(hints); len(whitelist) > 0 {
+		if whatlanggo.DetectWithOptions(text, whatlanggo.Options{Whitelist: whitelist}).Lang >= 0 {
+			options.Whitelist = whitelist
+		}
+	}
+
+	var candidates []Detection
+	for len(candidates) < n {
+		info := whatlanggo.DetectWithOptions(text, options)
+		if info.Lang < 0 || options.Blacklist[info.Lang] {
+			break
+		}
+		candidates = append(candidates, newDetection(info))
+		options.Blacklist[info.Lang] = true
+		// The blacklist is ignored with a whitelist, shrink the latter
+		if options.Whitelist != nil {
+			delete(options.Whitelist, info.Lang)
+			if len(options.Whitelist) == 0 {
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+func newDetection(info whatlanggo.Info) Detection {
+	detection := Detection{
+		Confidence: info.Confidence,
+		Script:     whatlanggo.Scripts[info.Script],
+	}
+	if info.Lang >= 0 {
+		detection.Lang = strings.ToUpper(info.Lang.Iso6391())
+	}
+	// Japanese is detected by the combined kana table, which has no name
+	if detection.Script == "" && info.Script != nil {
+		detection.Script = "Kana"
+	}
+	detection.SourceLang = deeplSourceLang(detection.Lang)
+	return detection
+}
+
+// deeplSourceLang returns the DeepL source language of an ISO 639-1 code,
+// empty when DeepL does not support it
+func deeplSourceLang(lang string) string {
+	if alias, ok := langAliases[lang]; ok {
+		lang = alias
+	}
+	for _, supported := range SourceLangs {
+		if lang == supported {
+			return lang
+		}
+	}
+	return ""
+}
+
+// hintedLangs returns the whatlanggo languages of DeepL language codes like
+// "EN" or "PT-BR"
+func hintedLangs(hints []string) map[whatlanggo.Lang]bool {
+	langs := map[whatlanggo.Lang]bool{}
+	for _, hint := range hints {
+		code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(hint)), "-")
+		if lang, ok := whatlangLangs[code]; ok {
+			langs[lang] = true
+		}
+	}
+	return langs
+}
+
+// ConfirmLanguage lets DeepL detect the language of text, by translating it
+// with the source language left to DeepL. The target language is English,
+// or German for texts detected locally as English, so that DeepL does not
+// skip the translation.
+func ConfirmLanguage(ctx context.Context, text string, proxyURL string, dlSession string) (string, error) {
+	targetLang := "EN"
+	if DetectLanguage(text, nil).SourceLang == "EN" {
+		targetLang = "DE"
+	}
+	_, result, err := handleTexts(ctx, AutoLang, targetLang, []TextItem{{Text: text}}, proxyURL, dlSession)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(result.Get("result.lang").String()), nil
+}
+
+// detectLang returns the source language of text sent upstream, the
+// detected DeepL source language or AutoLang when the language is not
+// supported by DeepL or detected with too little confidence
+func detectLang(ctx context.Context, text string, hints []string) string {
+	_, span := tracer.Start(ctx, "detect_language")
+	defer span.End()
+
+	detection := DetectLanguage(text, hints)
+	lang := detection.SourceLang
+	if lang == "" || detection.Confidence < getDetectConfidence() {
+		lang = AutoLang
+	}
+	span.SetAttributes(
+		attribute.Int("deeplx.characters", utf8.RuneCountInString(text)),
+		attribute.String("deeplx.detected_lang", detection.Lang),
+		attribute.Float64("deeplx.detect_confidence", detection.Confidence),
+		attribute.String("deeplx.source_lang", lang),
+	)
+	return lang
+}
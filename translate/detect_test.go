@@ -0,0 +1,108 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-21 18:02:47
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-21 18:02:47
+ * @FilePath: /DeepLX/translate/detect_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text       string
+		hints      []string
+		lang       string
+		sourceLang string
+		script     string
+		upstream   string
+	}{
+		{"Hello, how are you doing today? The weather is lovely.", nil, "EN", "EN", "Latin", "EN"},
+		{"こんにちは、元気ですか", nil, "JA", "JA", "Kana", "JA"},
+		// Too short to be sure
+		{"Hello", nil, "SO", "", "Latin", AutoLang},
+		{"Hello", []string{"en-US"}, "EN", "EN", "Latin", "EN"},
+		// Not supported by DeepL
+		{"यह एक परीक्षण वाक्य है", nil, "HI", "", "Devanagari", AutoLang},
+		// Nynorsk is read as Bokmål
+		{"Jeg liker å gå tur i skogen om morgenen", nil, "NN", "NB", "Latin", "NB"},
+		// Hints that do not fit the script are ignored
+		{"Привет, как дела?", []string{"EN"}, "RU", "RU", "Cyrillic", "RU"},
+		{"12345", nil, "", "", "", AutoLang},
+	}
+	for _, tt := range tests {
+		got := DetectLanguage(tt.text, tt.hints)
+		if got.Lang != tt.lang || got.SourceLang != tt.sourceLang || got.Script != tt.script {
+			t.Errorf("DetectLanguage(%q, %v) = %+v, want %s, %q, %s", tt.text, tt.hints, got, tt.lang, tt.sourceLang, tt.script)
+		}
+		if lang := detectLang(context.Background(), tt.text, tt.hints); lang != tt.upstream {
+			t.Errorf("detectLang(%q, %v) = %s, want %s", tt.text, tt.hints, lang, tt.upstream)
+		}
+	}
+}
+
+func TestDetectConfidence(t *testing.T) {
+	t.Cleanup(func() { SetDetectConfidence(DefaultDetectConfidence) })
+	text := "Guten Morgen, wie geht es dir heute?"
+	if lang := detectLang(context.Background(), text, nil); lang != "DE" {
+		t.Errorf("detectLang(%q) = %s, want DE", text, lang)
+	}
+	// A Dutch question read as Swedish is left to DeepL
+	if lang := detectLang(context.Background(), "Waar is het station?", nil); lang != AutoLang {
+		t.Errorf("detectLang(%q) = %s, want %s", "Waar is het station?", lang, AutoLang)
+	}
+	SetDetectConfidence(0.5)
+	if lang := detectLang(context.Background(), text, nil); lang != AutoLang {
+		t.Errorf("detectLang(%q) with a minimum confidence of 0.5 = %s, want %s", text, lang, AutoLang)
+	}
+}
+
+func TestDetectCandidates(t *testing.T) {
+	text := "Hello, how are you doing today? The weather is lovely."
+	candidates := DetectCandidates(text, nil, 3)
+	if len(candidates) != 3 || candidates[0] != DetectLanguage(text, nil) {
+		t.Fatalf("DetectCandidates(%q) = %+v, want 3 starting with the detected language", text, candidates)
+	}
+	if candidates[1].Lang == candidates[0].Lang || candidates[2].Lang == candidates[1].Lang {
+		t.Errorf("DetectCandidates(%q) = %+v, want distinct languages", text, candidates)
+	}
+
+	// Hints restrict the candidates
+	candidates = DetectCandidates(text, []string{"DE", "NL"}, 3)
+	if len(candidates) != 2 {
+		t.Fatalf("DetectCandidates(%q, [DE NL]) = %+v, want 2 candidates", text, candidates)
+	}
+	for _, candidate := range candidates {
+		if candidate.Lang != "DE" && candidate.Lang != "NL" {
+			t.Errorf("DetectCandidates(%q, [DE NL]) = %+v, want hinted languages only", text, candidates)
+		}
+	}
+
+	if candidates := DetectCandidates("안녕하세요", nil, 3); len(candidates) != 1 || candidates[0].Lang != "KO" {
+		t.Errorf("DetectCandidates of Hangul = %+v, want KO only", candidates)
+	}
+}
+
+func TestMetricsLang(t *testing.T) {
+	for lang, want := range map[string]string{
+		"de":      "DE",
+		"en-us":   "EN-US",
+		"":        AutoLang,
+		"Auto":    AutoLang,
+		"klingon": "other",
+		"DE-XX":   "other",
+	} {
+		if got := metricsLang(lang); got != want {
+			t.Errorf("metricsLang(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
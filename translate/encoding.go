@@ -0,0 +1,157 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 16:04:27
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 16:04:27
+ * @FilePath: /DeepLX/translate/encoding.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrInvalidResponse is returned when the upstream response body is not
+// valid JSON, even after decoding it
+var ErrInvalidResponse = errors.New("upstream returned an invalid JSON response")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decodeBody decodes a response body compressed as listed in its
+// Content-Encoding header. Stacked encodings like "gzip, br" are undone in
+// reverse order. Without header, gzip, zstd and zlib bodies are recognized
+// by their magic bytes, and a body that is not JSON is tried as brotli,
+// since some relays drop the header but keep the compression.
+func decodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	var encodings []string
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	if len(encodings) == 0 {
+		return sniffBody(body), nil
+	}
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoded, err := decode(body, encodings[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s response body: %w", encodings[i], err)
+		}
+		body = decoded
+	}
+	return body, nil
+}
+
+// decode undoes a single content encoding
+func decode(body []byte, encoding string) ([]byte, error) {
+	var reader io.Reader
+	switch encoding {
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		reader = r
+	case "deflate":
+		// deflate should be zlib wrapped, but raw deflate is common as well
+		if isZlib(body) {
+			r, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			reader = r
+		} else {
+			reader = flate.NewReader(bytes.NewReader(body))
+		}
+	case "zstd":
+		r, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		reader = r
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return io.ReadAll(reader)
+}
+
+// maxSniffedEncodings is how many stacked encodings sniffBody undoes
+const maxSniffedEncodings = 4
+
+// sniffBody decodes a body sent without Content-Encoding header that is
+// nevertheless compressed, and returns any other body unchanged
+func sniffBody(body []byte) []byte {
+	for i := 0; i < maxSniffedEncodings && !looksLikeJSON(body); i++ {
+		decoded, ok := sniffEncoding(body)
+		if !ok {
+			break
+		}
+		body = decoded
+	}
+	return body
+}
+
+// sniffEncoding undoes the encoding recognized from the start of body
+func sniffEncoding(body []byte) ([]byte, bool) {
+	var encoding string
+	switch {
+	case bytes.HasPrefix(body, gzipMagic):
+		encoding = "gzip"
+	case bytes.HasPrefix(body, zstdMagic):
+		encoding = "zstd"
+	case isZlib(body):
+		encoding = "deflate"
+	default:
+		// Brotli has no magic bytes, so only a valid JSON result counts
+		if decoded, err := decode(body, "br"); err == nil && looksLikeJSON(decoded) {
+			return decoded, true
+		}
+		return nil, false
+	}
+	decoded, err := decode(body, encoding)
+	return decoded, err == nil
+}
+
+// looksLikeJSON reports whether body starts like a JSON object or array
+func looksLikeJSON(body []byte) bool {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// isZlib reports whether body starts with a zlib header
+func isZlib(body []byte) bool {
+	return len(body) >= 2 && body[0]&0x0f == 8 && (uint16(body[0])<<8|uint16(body[1]))%31 == 0
+}
+
+// bodySnippet returns the start of body for logging, at most maxLen bytes
+// and with invalid UTF-8 replaced
+func bodySnippet(body []byte, maxLen int) string {
+	if len(body) <= maxLen {
+		return strings.ToValidUTF8(string(body), "�")
+	}
+	return strings.ToValidUTF8(string(body[:maxLen]), "�") + "…"
+}
@@ -0,0 +1,124 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 16:31:55
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 16:31:55
+ * @FilePath: /DeepLX/translate/encoding_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const testBody = `{"jsonrpc":"2.0","result":{"lang":"EN","texts":[{"text":"Hallo"}]}}`
+
+// compress compresses body with encoding for the tests
+func compress(t *testing.T, body []byte, encoding string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&out)
+	case "br":
+		w = brotli.NewWriter(&out)
+	case "deflate":
+		w, _ = flate.NewWriter(&out, flate.DefaultCompression)
+	case "zlib":
+		w = zlib.NewWriter(&out)
+	case "zstd":
+		var err error
+		if w, err = zstd.NewWriter(&out); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("unknown encoding %q", encoding)
+	}
+	w.Write(body)
+	w.Close()
+	return out.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name            string
+		encodings       []string
+		contentEncoding string
+	}{
+		{"identity", nil, ""},
+		{"identity header", nil, "identity"},
+		{"gzip", []string{"gzip"}, "gzip"},
+		{"br", []string{"br"}, "br"},
+		{"raw deflate", []string{"deflate"}, "deflate"},
+		{"zlib deflate", []string{"zlib"}, "deflate"},
+		{"zstd", []string{"zstd"}, "zstd"},
+		{"upper case", []string{"zstd"}, "ZSTD"},
+		{"stacked", []string{"gzip", "br"}, "gzip, br"},
+		{"stacked with identity", []string{"zstd", "gzip"}, "identity,zstd,gzip"},
+		{"sniffed gzip", []string{"gzip"}, ""},
+		{"sniffed zstd", []string{"zstd"}, ""},
+		{"sniffed zlib", []string{"zlib"}, ""},
+		{"sniffed br", []string{"br"}, ""},
+		{"sniffed stacked", []string{"gzip", "zstd"}, ""},
+	}
+	for _, tt := range tests {
+		body := []byte(testBody)
+		for _, encoding := range tt.encodings {
+			body = compress(t, body, encoding)
+		}
+		got, err := decodeBody(body, tt.contentEncoding)
+		if err != nil {
+			t.Errorf("%s: decodeBody() error = %v", tt.name, err)
+			continue
+		}
+		if string(got) != testBody {
+			t.Errorf("%s: decodeBody() = %q, want %q", tt.name, got, testBody)
+		}
+	}
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	if _, err := decodeBody([]byte(testBody), "gzip"); err == nil {
+		t.Error("decodeBody() of a plain body declared gzip succeeded")
+	}
+	if _, err := decodeBody(compress(t, []byte(testBody), "gzip"), "compress"); err == nil {
+		t.Error("decodeBody() with an unsupported encoding succeeded")
+	}
+
+	// An undecodable body without header is returned unchanged
+	garbage := []byte("<html>Bad Gateway</html>")
+	got, err := decodeBody(garbage, "")
+	if err != nil || !bytes.Equal(got, garbage) {
+		t.Errorf("decodeBody() = %q, %v, want the body unchanged", got, err)
+	}
+}
+
+func TestBodySnippet(t *testing.T) {
+	if got := bodySnippet([]byte("short"), 10); got != "short" {
+		t.Errorf("bodySnippet() = %q, want %q", got, "short")
+	}
+	if got := bodySnippet([]byte(strings.Repeat("a", 20)), 10); got != strings.Repeat("a", 10)+"…" {
+		t.Errorf("bodySnippet() = %q, want it truncated to 10 bytes", got)
+	}
+	if got := bodySnippet([]byte("äöü"), 3); got != "ä�…" {
+		t.Errorf("bodySnippet() = %q, want the cut rune replaced", got)
+	}
+	if got := bodySnippet([]byte{0x1f, 0x8b, 'a'}, 10); !strings.HasSuffix(got, "a") || strings.ContainsRune(got, 0x8b) {
+		t.Errorf("bodySnippet() = %q, want invalid UTF-8 replaced", got)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 17:10:46
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 17:10:46
+ * @FilePath: /DeepLX/translate/errors.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Kinds of upstream errors, matched with errors.Is
+var (
+	// ErrRateLimited is returned when DeepL rate limited the request
+	ErrRateLimited = errors.New("too many requests")
+	// ErrUnsupportedLanguage is returned when DeepL does not support the
+	// source or target language
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	// ErrQuotaExceeded is returned when the character quota of the account
+	// is used up
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrInvalidRequest is returned when DeepL rejected the request parameters
+	ErrInvalidRequest = errors.New("invalid request")
+)
+
+// StatusQuotaExceeded is the status of the official API for an exceeded quota
+const StatusQuotaExceeded = 456
+
+// UpstreamError is a JSON-RPC error object returned by DeepL, or the error
+// status of another backend
+type UpstreamError struct {
+	// Backend is the name of the backend, empty for DeepL's web endpoint
+	Backend string
+	// Code is the JSON-RPC error code, or the HTTP status of other backends
+	Code    int64
+	Message string
+	// Data is the raw JSON of error.data, empty when missing
+	Data string
+	// Kind is one of the Err variables, nil for unknown errors
+	Kind error
+}
+
+func (e *UpstreamError) Error() string {
+	backend := e.Backend
+	if backend == "" {
+		backend = "DeepL"
+	}
+	message := fmt.Sprintf("%s error %d: %s", backend, e.Code, e.Message)
+	if detail := e.detail(); detail != "" {
+		message += " (" + detail + ")"
+	}
+	return message
+}
+
+// Unwrap returns the kind of the error
+func (e *UpstreamError) Unwrap() error {
+	return e.Kind
+}
+
+// detail returns error.data as text, or its "what" field when it is an object
+func (e *UpstreamError) detail() string {
+	if e.Data == "" {
+		return ""
+	}
+	data := gjson.Parse(e.Data)
+	if what := data.Get("what"); what.Exists() {
+		return what.String()
+	}
+	if data.Type == gjson.String {
+		return data.String()
+	}
+	return e.Data
+}
+
+// Codes of DeepL JSON-RPC errors as observed from the web endpoint, the
+// remaining errors are classified by their message
+var upstreamErrorKinds = map[int64]error{
+	1042911: ErrRateLimited,
+	1042912: ErrRateLimited,
+	1156049: ErrQuotaExceeded,
+	-32600:  ErrInvalidRequest,
+	-32602:  ErrInvalidRequest,
+}
+
+// parseUpstreamError returns the error object of a JSON-RPC response, or nil
+// when the response has none
+func parseUpstreamError(response gjson.Result) *UpstreamError {
+	object := response.Get("error")
+	if !object.IsObject() {
+		return nil
+	}
+	e := &UpstreamError{
+		Code:    object.Get("code").Int(),
+		Message: object.Get("message").String(),
+	}
+	if data := object.Get("data"); data.Exists() {
+		e.Data = data.Raw
+	}
+
+	text := strings.ToLower(e.Message + " " + e.detail())
+	switch {
+	case strings.Contains(text, "lang") && (strings.Contains(text, "not supported") || strings.Contains(text, "unsupported")):
+		e.Kind = ErrUnsupportedLanguage
+	case upstreamErrorKinds[e.Code] != nil:
+		e.Kind = upstreamErrorKinds[e.Code]
+	case strings.Contains(text, "too many requests"):
+		e.Kind = ErrRateLimited
+	case strings.Contains(text, "quota"):
+		e.Kind = ErrQuotaExceeded
+	}
+	return e
+}
+
+// ErrorStatus returns the HTTP status reported to clients for a failed
+// translation
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrQuotaExceeded):
+		return StatusQuotaExceeded
+	case errors.Is(err, ErrUnsupportedLanguage), errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest
+	}
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		return http.StatusBadGateway
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusServiceUnavailable
+}
+
+// errorResult returns the result of a translation that failed with err
+func errorResult(err error) DeepLXTranslationResult {
+	return DeepLXTranslationResult{
+		Code:    ErrorStatus(err),
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 17:42:18
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 17:42:18
+ * @FilePath: /DeepLX/translate/errors_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestParseUpstreamError(t *testing.T) {
+	tests := []struct {
+		response string
+		kind     error
+		message  string
+	}{
+		{`{"jsonrpc":"2.0","error":{"code":1042912,"message":"Too many requests"}}`, ErrRateLimited, "DeepL error 1042912: Too many requests"},
+		{`{"jsonrpc":"2.0","error":{"code":1042911,"message":"Too many requests."}}`, ErrRateLimited, "DeepL error 1042911: Too many requests."},
+		{`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request","data":{"what":"Value for 'params.lang.target_lang' not supported."}}}`, ErrUnsupportedLanguage, "DeepL error -32600: Invalid Request (Value for 'params.lang.target_lang' not supported.)"},
+		{`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"texts"}}`, ErrInvalidRequest, "DeepL error -32602: Invalid params (texts)"},
+		{`{"jsonrpc":"2.0","error":{"code":1156049,"message":"Quota Exceeded"}}`, ErrQuotaExceeded, "DeepL error 1156049: Quota Exceeded"},
+		{`{"jsonrpc":"2.0","error":{"code":1,"message":"Character quota used up"}}`, ErrQuotaExceeded, "DeepL error 1: Character quota used up"},
+		{`{"jsonrpc":"2.0","error":{"code":2,"message":"Oops","data":[1,2]}}`, nil, "DeepL error 2: Oops ([1,2])"},
+	}
+	for _, tt := range tests {
+		err := parseUpstreamError(gjson.Parse(tt.response))
+		if err == nil {
+			t.Errorf("parseUpstreamError(%s) = nil", tt.response)
+			continue
+		}
+		if err.Kind != tt.kind {
+			t.Errorf("parseUpstreamError(%s) kind = %v, want %v", tt.response, err.Kind, tt.kind)
+		}
+		if err.Error() != tt.message {
+			t.Errorf("parseUpstreamError(%s) = %q, want %q", tt.response, err.Error(), tt.message)
+		}
+	}
+
+	for _, response := range []string{
+		`{"jsonrpc":"2.0","result":{"texts":[]}}`,
+		`{"jsonrpc":"2.0","error":null}`,
+	} {
+		if err := parseUpstreamError(gjson.Parse(response)); err != nil {
+			t.Errorf("parseUpstreamError(%s) = %v, want nil", response, err)
+		}
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&UpstreamError{Kind: ErrRateLimited}, http.StatusTooManyRequests},
+		{fmt.Errorf("%w, blocked", ErrRateLimited), http.StatusTooManyRequests},
+		{&UpstreamError{Kind: ErrQuotaExceeded}, StatusQuotaExceeded},
+		{&UpstreamError{Kind: ErrUnsupportedLanguage}, http.StatusBadRequest},
+		{&UpstreamError{Kind: ErrInvalidRequest}, http.StatusBadRequest},
+		{&UpstreamError{}, http.StatusBadGateway},
+		{ErrInvalidResponse, http.StatusServiceUnavailable},
+		{errors.New("connection refused"), http.StatusServiceUnavailable},
+		{fmt.Errorf("request failed: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		if got := ErrorStatus(tt.err); got != tt.want {
+			t.Errorf("ErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
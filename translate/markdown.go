@@ -0,0 +1,277 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 17:34:12
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 17:34:12
+ * @FilePath: /DeepLX/translate/markdown.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	markdownFence       = regexp.MustCompile("^\\s{0,3}(```+|~~~+)")
+	markdownPrefix      = regexp.MustCompile(`^\s*(?:(?:#{1,6}|[-*+]|\d+[.)]|>)\s+(?:\[[ xX]\]\s+)?)*`)
+	markdownTableRule   = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	markdownThematic    = regexp.MustCompile(`^\s{0,3}([-*_=])(?:\s*[-*_=]){2,}\s*$`)
+	markdownReference   = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s`)
+	markdownHTMLBlock   = regexp.MustCompile(`^\s{0,3}<[a-zA-Z/!?]`)
+	markdownLink        = regexp.MustCompile(`^(!?\[)([^\]]*)(\]\([^)]*\)|\]\[[^\]]*\])$`)
+	markdownLineBreak   = regexp.MustCompile(`\n[ \t>]*`)
+	markdownBreakMark   = regexp.MustCompile(`[ \t]*⟦\s*(\d+)\s*⟧[ \t]*`)
+	markdownInlineToken = regexp.MustCompile("``[^`]*``|`[^`]+`|!?\\[[^\\]]*\\](?:\\([^)]*\\)|\\[[^\\]]*\\])|<[a-zA-Z/!][^>]*>|https?://[^\\s)>\\]]+|⟦\\s*\\d+\\s*⟧")
+)
+
+// markdownPart is a piece of a Markdown document, either kept verbatim or
+// prose to translate
+type markdownPart struct {
+	text  string
+	prose bool
+}
+
+// markdownDocument is a Markdown document split into verbatim and prose pieces
+type markdownDocument []markdownPart
+
+func (d *markdownDocument) keep(s string) {
+	if s != "" {
+		*d = append(*d, markdownPart{text: s})
+	}
+}
+
+// add appends prose, keeping its surrounding whitespace verbatim
+func (d *markdownDocument) add(s string) {
+	core := strings.TrimLeftFunc(s, unicode.IsSpace)
+	d.keep(s[:len(s)-len(core)])
+	trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+	if trimmed != "" {
+		*d = append(*d, markdownPart{text: trimmed, prose: true})
+	}
+	d.keep(core[len(trimmed):])
+}
+
+// extend appends a continuation line to the prose piece ending the
+// document, see continuable. The line break and the prefix of the line stay
+// in the prose, to be masked by maskMarkdownInline.
+func (d *markdownDocument) extend(prefix, s string) {
+	*d = (*d)[:len(*d)-1]
+	last := &(*d)[len(*d)-1]
+	core := strings.TrimRightFunc(s, unicode.IsSpace)
+	last.text += "\n" + prefix + core
+	d.keep(s[len(core):])
+}
+
+// continuable reports whether the document ends with a prose line that a
+// following plain line continues. Hard line breaks end the paragraph line.
+func (d markdownDocument) continuable() bool {
+	if len(d) < 2 || !d[len(d)-2].prose {
+		return false
+	}
+	return d[len(d)-1].text == "\n" && !strings.HasSuffix(d[len(d)-2].text, `\`)
+}
+
+// parseMarkdown splits a Markdown document into prose and the parts that
+// must not be translated: front matter, fenced and indented code, HTML
+// blocks, link reference definitions, thematic breaks and block markers.
+// Lines continuing a paragraph are joined into a single prose piece that
+// keeps their line breaks.
+func parseMarkdown(text string) markdownDocument {
+	var d markdownDocument
+	lines := strings.SplitAfter(text, "\n")
+
+	fence, frontMatter := "", ""
+	if len(lines) > 0 {
+		if first := strings.TrimSpace(lines[0]); first == "---" || first == "+++" {
+			frontMatter = first
+		}
+	}
+	previousBlank, indented, html, paragraph := true, false, false, false
+	quoteDepth := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indented = (previousBlank || indented) && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") || (indented && trimmed == ""))
+		html = (html && trimmed != "") || (previousBlank && markdownHTMLBlock.MatchString(line))
+
+		switch {
+		case frontMatter != "":
+			d.keep(line)
+			if i > 0 && (trimmed == frontMatter || trimmed == "...") {
+				frontMatter = ""
+			}
+		case fence != "":
+			d.keep(line)
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case markdownFence.MatchString(line):
+			d.keep(line)
+			fence = markdownFence.FindStringSubmatch(line)[1]
+		case trimmed == "",
+			html,
+			indented,
+			markdownThematic.MatchString(line),
+			markdownReference.MatchString(line),
+			markdownTableRule.MatchString(line) && strings.Contains(line, "-"):
+			d.keep(line)
+		case strings.HasPrefix(trimmed, "|"):
+			cells := strings.Split(line, "|")
+			for j, cell := range cells {
+				if j > 0 {
+					d.keep("|")
+				}
+				d.add(cell)
+			}
+		default:
+			prefix := markdownPrefix.FindString(line)
+			depth := strings.Count(prefix, ">")
+			if paragraph && strings.Trim(prefix, "> \t") == "" && (depth == quoteDepth || depth == 0) && d.continuable() {
+				d.extend(prefix, line[len(prefix):])
+				break
+			}
+			d.keep(prefix)
+			d.add(line[len(prefix):])
+			paragraph = !strings.HasPrefix(strings.TrimLeft(prefix, "> \t"), "#")
+			quoteDepth = depth
+			previousBlank = false
+			continue
+		}
+
+		paragraph = false
+		previousBlank = trimmed == ""
+	}
+	return d
+}
+
+// maskMarkdownInline replaces inline code, link destinations, inline HTML
+// and URLs in prose with sentinels, leaving link texts translatable. Soft
+// line breaks become sentinels separated by spaces from the words around
+// them.
+func maskMarkdownInline(m *masker, prose string) string {
+	masked := markdownInlineToken.ReplaceAllStringFunc(prose, func(token string) string {
+		if link := markdownLink.FindStringSubmatch(token); link != nil {
+			return m.sentinel(link[1]) + link[2] + m.sentinel(link[3])
+		}
+		return m.sentinel(token)
+	})
+	return markdownLineBreak.ReplaceAllStringFunc(masked, func(lineBreak string) string {
+		return " " + m.sentinel(lineBreak) + " "
+	})
+}
+
+// isLineBreak reports whether sentinel n stands in for a soft line break
+func (m *masker) isLineBreak(n int) bool {
+	return n < len(m.spans) && strings.HasPrefix(m.spans[n], "\n")
+}
+
+// unmaskMarkdown restores the sentinels of a translated prose piece. Line
+// breaks take the place of the spaces around their sentinel, and a line
+// break dropped by the translator is not an error, the lines are then
+// joined by a space.
+func unmaskMarkdown(m *masker, translation, segment string) (string, error) {
+	translation = markdownBreakMark.ReplaceAllStringFunc(translation, func(mark string) string {
+		n, _ := strconv.Atoi(markdownBreakMark.FindStringSubmatch(mark)[1])
+		if m.isLineBreak(n) {
+			return strings.TrimSpace(mark)
+		}
+		return mark
+	})
+	var expected []int
+	for _, n := range sentinelsIn(segment) {
+		if !m.isLineBreak(n) {
+			expected = append(expected, n)
+		}
+	}
+	return m.unmask(translation, expected)
+}
+
+// TranslateMarkdownWith translates the prose of a Markdown document with fn
+// and re-renders it with the same structure. Code, front matter, HTML, link
+// destinations and URLs are never passed to fn. Prose whose protected spans
+// did not survive translation is kept untranslated.
+func TranslateMarkdownWith(text string, fn func(segments []string) ([]string, error)) (string, error) {
+	d := parseMarkdown(text)
+
+	m := &masker{}
+	var segments []string
+	for i := range d {
+		if d[i].prose {
+			segments = append(segments, maskMarkdownInline(m, d[i].text))
+		}
+	}
+	if len(segments) == 0 {
+		return text, nil
+	}
+
+	translations, err := fn(segments)
+	if err != nil {
+		return "", err
+	}
+	if len(translations) != len(segments) {
+		return "", fmt.Errorf("expected %d translations, got %d", len(segments), len(translations))
+	}
+
+	var out strings.Builder
+	n := 0
+	for _, part := range d {
+		if !part.prose {
+			out.WriteString(part.text)
+			continue
+		}
+		restored, err := unmaskMarkdown(m, translations[n], segments[n])
+		if err != nil {
+			restored = part.text
+		}
+		out.WriteString(restored)
+		n++
+	}
+	return out.String(), nil
+}
+
+// translateMarkdown translates a Markdown document, see TranslateMarkdownWith
+func translateMarkdown(ctx context.Context, sourceLang, targetLang, text string, proxyURL string, dlSession string) (DeepLXTranslationResult, error) {
+	var batch DeepLXTranslationResult
+	data, err := TranslateMarkdownWith(text, func(segments []string) ([]string, error) {
+		var err error
+		batch, err = TranslateTexts(ctx, sourceLang, nil, targetLang, segments, "", proxyURL, dlSession)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Code != http.StatusOK {
+			return nil, fmt.Errorf("%s", batch.Message)
+		}
+		return batch.Texts, nil
+	})
+	if err != nil {
+		if batch.Code != 0 && batch.Code != http.StatusOK {
+			return DeepLXTranslationResult{
+				Code:    batch.Code,
+				Message: batch.Message,
+			}, nil
+		}
+		return DeepLXTranslationResult{}, err
+	}
+
+	if batch.SourceLang != "" {
+		sourceLang = batch.SourceLang
+	}
+	return DeepLXTranslationResult{
+		Code:       http.StatusOK,
+		ID:         batch.ID,
+		Data:       data,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Method:     map[bool]string{true: "Pro", false: "Free"}[dlSession != ""],
+	}, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 16:24:50
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 16:24:50
+ * @FilePath: /DeepLX/translate/markdown_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// upperCase "translates" segments by upper-casing them, sentinels survive it
+func upperCase(segments []string) ([]string, error) {
+	translations := make([]string, len(segments))
+	for i, segment := range segments {
+		translations[i] = strings.ToUpper(segment)
+	}
+	return translations, nil
+}
+
+func TestTranslateMarkdownWith(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"headings", "# Title\n\n## Sub title ##\n", "# TITLE\n\n## SUB TITLE ##\n"},
+		{"lists", "- one\n- [x] two\n  1. three\n", "- ONE\n- [x] TWO\n  1. THREE\n"},
+		{"block quote", "> quoted text\n> more\n", "> QUOTED TEXT\n> MORE\n"},
+		{"code fence", "Run:\n\n```go\nfmt.Println(\"hi\")\n```\n\nDone.", "RUN:\n\n```go\nfmt.Println(\"hi\")\n```\n\nDONE."},
+		{"indented code", "Text\n\n    code here\n\nEnd", "TEXT\n\n    code here\n\nEND"},
+		{"inline code", "Call `foo()` now", "CALL `foo()` NOW"},
+		{"links", "See [the docs](https://example.com/a) and ![logo](img.png)", "SEE [THE DOCS](https://example.com/a) AND ![LOGO](img.png)"},
+		{"bare URL", "Go to https://example.com/path now", "GO TO https://example.com/path NOW"},
+		{"front matter", "---\ntitle: x\n---\nBody", "---\ntitle: x\n---\nBODY"},
+		{"soft line breaks", "first line\nsecond line\n   third line\n", "FIRST LINE\nSECOND LINE\n   THIRD LINE\n"},
+		{"hard break with spaces", "first line  \nsecond line", "FIRST LINE  \nSECOND LINE"},
+		{"hard break with backslash", "first line\\\nsecond line", "FIRST LINE\\\nSECOND LINE"},
+		{"table", "| a | b |\n|---|---|\n| c | d |\n", "| A | B |\n|---|---|\n| C | D |\n"},
+	}
+	for _, tt := range tests {
+		got, err := TranslateMarkdownWith(tt.text, upperCase)
+		if err != nil {
+			t.Errorf("%s: TranslateMarkdownWith() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: TranslateMarkdownWith(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateMarkdownSegments(t *testing.T) {
+	var segments []string
+	_, err := TranslateMarkdownWith("Some `code` and\na [link](https://x.y).\n\n```\nnot this\n```\n", func(s []string) ([]string, error) {
+		segments = s
+		return s, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Some ⟦0⟧ and ⟦3⟧ a ⟦1⟧link⟦2⟧."}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("segments = %q, want %q", segments, want)
+	}
+}
+
+func TestTranslateMarkdownLostSentinels(t *testing.T) {
+	tests := []struct {
+		name        string
+		translation string
+		want        string
+	}{
+		{"line break dropped", "erste ⟦0⟧ Zeile zweite Zeile", "erste `x` Zeile zweite Zeile"},
+		{"line break moved", "erste ⟦0⟧ Zeile ⟦1⟧ zweite Zeile", "erste `x` Zeile\nzweite Zeile"},
+		{"inline code dropped", "erste Zeile ⟦1⟧ zweite Zeile", "first `x` line\nsecond line"},
+	}
+	for _, tt := range tests {
+		got, err := TranslateMarkdownWith("first `x` line\nsecond line", func(s []string) ([]string, error) {
+			return []string{tt.translation}, nil
+		})
+		if err != nil || got != tt.want {
+			t.Errorf("%s: TranslateMarkdownWith() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestTranslateMarkdown(t *testing.T) {
+	newFakeUpstream(t)
+	result, err := TranslateByDeepLX(context.Background(), "EN", nil, "DE", "# Title\n\nSome text\nwrapped.\n", "markdown", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# [DE] Title\n\n[DE] Some text\nwrapped.\n"; result.Data != want {
+		t.Errorf("Data = %q, want %q", result.Data, want)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 17:20:44
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 17:20:44
+ * @FilePath: /DeepLX/translate/mask.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// sentinelPattern matches a sentinel, tolerating spaces added by the translator
+var sentinelPattern = regexp.MustCompile(`⟦\s*(\d+)\s*⟧`)
+
+// masker replaces spans that must survive translation unchanged with
+// numbered sentinels, and puts them back afterwards
+type masker struct {
+	spans []string
+}
+
+// sentinel stores span and returns the sentinel standing in for it
+func (m *masker) sentinel(span string) string {
+	m.spans = append(m.spans, span)
+	return fmt.Sprintf("⟦%d⟧", len(m.spans)-1)
+}
+
+// unmask restores the spans of the sentinels in text. It fails when a
+// sentinel expected in text is missing or appears more than once, with one
+// joined error per such sentinel.
+func (m *masker) unmask(text string, expected []int) (string, error) {
+	seen := make(map[int]int)
+	restored := sentinelPattern.ReplaceAllStringFunc(text, func(s string) string {
+		n, err := strconv.Atoi(sentinelPattern.FindStringSubmatch(s)[1])
+		if err != nil || n >= len(m.spans) {
+			return s
+		}
+		seen[n]++
+		return m.spans[n]
+	})
+
+	var errs []error
+	for _, n := range expected {
+		switch seen[n] {
+		case 0:
+			errs = append(errs, fmt.Errorf("placeholder %q is missing from the translation", m.spans[n]))
+		case 1:
+		default:
+			errs = append(errs, fmt.Errorf("placeholder %q is duplicated in the translation", m.spans[n]))
+		}
+	}
+	return restored, errors.Join(errs...)
+}
+
+// sentinelsIn returns the numbers of the sentinels in text
+func sentinelsIn(text string) []int {
+	var numbers []int
+	for _, match := range sentinelPattern.FindAllStringSubmatch(text, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
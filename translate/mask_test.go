@@ -0,0 +1,60 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 16:58:31
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 16:58:31
+ * @FilePath: /DeepLX/translate/mask_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMasker(t *testing.T) {
+	m := &masker{}
+	masked := "Hello " + m.sentinel("{name}") + ", you have " + m.sentinel("%d") + " messages"
+	if masked != "Hello ⟦0⟧, you have ⟦1⟧ messages" {
+		t.Fatalf("masked = %q", masked)
+	}
+	expected := sentinelsIn(masked)
+	if !reflect.DeepEqual(expected, []int{0, 1}) {
+		t.Fatalf("sentinelsIn() = %v", expected)
+	}
+
+	tests := []struct {
+		name        string
+		translation string
+		want        string
+		errs        []string
+	}{
+		{"restored", "Hallo ⟦0⟧, du hast ⟦1⟧ Nachrichten", "Hallo {name}, du hast %d Nachrichten", nil},
+		{"reordered with spaces", "⟦ 1 ⟧ Nachrichten für ⟦0 ⟧", "%d Nachrichten für {name}", nil},
+		{"missing", "Hallo, du hast ⟦1⟧ Nachrichten", "Hallo, du hast %d Nachrichten", []string{`"{name}" is missing`}},
+		{"duplicated", "⟦0⟧ ⟦0⟧ hat ⟦1⟧", "{name} {name} hat %d", []string{`"{name}" is duplicated`}},
+		{"both", "⟦1⟧ ⟦1⟧", "%d %d", []string{`"{name}" is missing`, `"%d" is duplicated`}},
+		{"unknown sentinel kept", "⟦0⟧ ⟦7⟧ ⟦1⟧", "{name} ⟦7⟧ %d", nil},
+	}
+	for _, tt := range tests {
+		got, err := m.unmask(tt.translation, expected)
+		if got != tt.want {
+			t.Errorf("%s: unmask() = %q, want %q", tt.name, got, tt.want)
+		}
+		if (err != nil) != (tt.errs != nil) {
+			t.Errorf("%s: unmask() error = %v, want %v", tt.name, err, tt.errs)
+			continue
+		}
+		for _, want := range tt.errs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: unmask() error = %v, want it to contain %q", tt.name, err, want)
+			}
+		}
+	}
+}
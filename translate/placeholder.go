@@ -0,0 +1,317 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-19 18:24:51
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-19 23:18:40
+ * @FilePath: /DeepLX/translate/placeholder.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder styles recognized by PlaceholderOptions.Styles
+const (
+	PlaceholderPrintf   = "printf"
+	PlaceholderICU      = "icu"
+	PlaceholderMustache = "mustache"
+)
+
+// placeholderPatterns are the regular expressions of the regex based styles
+var placeholderPatterns = map[string]*regexp.Regexp{
+	PlaceholderPrintf:   regexp.MustCompile(`%%|%\([\w.]+\)[-+#0]*\d*(?:\.\d+)?[diouxXeEfFgGcrsa]|%(?:\d+\$)?[-+#0']*(?:\d+|\*)?(?:\.(?:\d+|\*))?(?:hh|h|ll|l|L|q|j|z|t)?[diouxXeEfFgGaAcspn@]`),
+	PlaceholderMustache: regexp.MustCompile(`\{\{\{?[^{}]*\}?\}\}`),
+}
+
+var (
+	icuName = regexp.MustCompile(`^\s*[\w.]+\s*$`)
+	icuType = regexp.MustCompile(`^\s*(?:number|date|time|spellout|ordinal|duration)\s*$`)
+)
+
+// PlaceholderOptions controls which placeholders are protected from translation
+type PlaceholderOptions struct {
+	// Styles lists the placeholder styles to protect, all of them when empty
+	Styles []string
+	// Patterns are additional regular expressions of spans to protect
+	Patterns []string
+	// Strict fails the translation when a placeholder is missing or
+	// duplicated in the output, instead of reporting a warning
+	Strict bool
+	// Warn receives the warnings of ProtectPlaceholders when Strict is not
+	// set, which drops them when Warn is nil
+	Warn func(warning string)
+}
+
+type span struct {
+	start, end int
+}
+
+// parseICUContent parses the content of a plural or select case starting at
+// i, up to the closing brace whose index it returns
+func parseICUContent(text string, i int, spans *[]span) (int, bool) {
+	for i < len(text) {
+		switch text[i] {
+		case '{':
+			end, ok := parseICU(text, i, spans)
+			if !ok {
+				return 0, false
+			}
+			i = end
+			continue
+		case '}':
+			return i, true
+		case '#':
+			*spans = append(*spans, span{i, i + 1})
+		}
+		i++
+	}
+	return 0, false
+}
+
+// parseICU parses the ICU argument starting with the brace at i and returns
+// the index after it. Simple arguments like {name} or {n, number} are
+// protected as a whole; for plural and select arguments only the syntax is
+// protected so that the text of every case stays translatable.
+func parseICU(text string, i int, spans *[]span) (int, bool) {
+	j := i + 1
+	k := strings.IndexAny(text[j:], ",{}")
+	if k < 0 || text[j+k] == '{' || !icuName.MatchString(text[j:j+k]) {
+		return 0, false
+	}
+	j += k
+	if text[j] == '}' {
+		*spans = append(*spans, span{i, j + 1})
+		return j + 1, true
+	}
+
+	j++
+	k = strings.IndexAny(text[j:], ",{}")
+	if k < 0 || text[j+k] == '{' {
+		return 0, false
+	}
+	kind := strings.TrimSpace(text[j : j+k])
+	j += k
+	switch kind {
+	case "plural", "select", "selectordinal":
+	default:
+		if !icuType.MatchString(kind) {
+			return 0, false
+		}
+		k = strings.IndexByte(text[j:], '}')
+		if k < 0 {
+			return 0, false
+		}
+		*spans = append(*spans, span{i, j + k + 1})
+		return j + k + 1, true
+	}
+	if text[j] == '}' {
+		return 0, false
+	}
+
+	var local []span
+	start := i
+	j++
+	for {
+		k = strings.IndexAny(text[j:], "{}")
+		if k < 0 {
+			return 0, false
+		}
+		if text[j+k] == '}' {
+			local = append(local, span{start, j + k + 1})
+			*spans = append(*spans, local...)
+			return j + k + 1, true
+		}
+		if strings.TrimSpace(text[j:j+k]) == "" {
+			return 0, false
+		}
+		local = append(local, span{start, j + k + 1})
+		end, ok := parseICUContent(text, j+k+1, &local)
+		if !ok {
+			return 0, false
+		}
+		start, j = end, end+1
+	}
+}
+
+// icuSpans returns the spans of ICU message syntax in text
+func icuSpans(text string) []span {
+	var spans []span
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			continue
+		}
+		// Leave Mustache tags alone
+		if strings.HasPrefix(text[i:], "{{") {
+			for i+1 < len(text) && text[i+1] == '{' {
+				i++
+			}
+			continue
+		}
+		if end, ok := parseICU(text, i, &spans); ok {
+			i = end - 1
+		}
+	}
+	return spans
+}
+
+// maskPlaceholders replaces the placeholders in text with sentinels
+func maskPlaceholders(m *masker, text string, opts PlaceholderOptions) (string, error) {
+	styles := opts.Styles
+	if len(styles) == 0 {
+		styles = []string{PlaceholderPrintf, PlaceholderICU, PlaceholderMustache}
+	}
+
+	patterns := []*regexp.Regexp{sentinelPattern}
+	var spans []span
+	for _, style := range styles {
+		switch style {
+		case PlaceholderICU:
+			spans = append(spans, icuSpans(text)...)
+		case PlaceholderPrintf, PlaceholderMustache:
+			patterns = append(patterns, placeholderPatterns[style])
+		default:
+			return "", fmt.Errorf("unknown placeholder style %q", style)
+		}
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid placeholder pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	for _, re := range patterns {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, span{loc[0], loc[1]})
+			}
+		}
+	}
+
+	// Earlier and longer spans win, overlapping ones are dropped and
+	// adjacent ones are merged into a single sentinel
+	sort.Slice(spans, func(a, b int) bool {
+		if spans[a].start != spans[b].start {
+			return spans[a].start < spans[b].start
+		}
+		return spans[a].end > spans[b].end
+	})
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.start == merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, s := range merged {
+		out.WriteString(text[last:s.start])
+		out.WriteString(m.sentinel(text[s.start:s.end]))
+		last = s.end
+	}
+	out.WriteString(text[last:])
+	return out.String(), nil
+}
+
+// TranslateWithPlaceholders translates req.Text with t, but masks printf,
+// ICU, Mustache and custom placeholders with sentinels before the upstream
+// call and restores them afterwards. Placeholders missing or
+// duplicated in the translation are reported in Warnings, or fail the
+// translation when opts.Strict is set.
+func TranslateWithPlaceholders(ctx context.Context, t Translator, req Request, opts PlaceholderOptions) (DeepLXTranslationResult, error) {
+	m := &masker{}
+	masked, err := maskPlaceholders(m, req.Text, opts)
+	if err != nil {
+		return DeepLXTranslationResult{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}, nil
+	}
+	expected := sentinelsIn(masked)
+
+	req.Text = masked
+	result, err := t.Translate(ctx, req)
+	if err != nil || result.Code != http.StatusOK {
+		return result, err
+	}
+
+	data, err := m.unmask(result.Data, expected)
+	if err != nil {
+		if opts.Strict {
+			return DeepLXTranslationResult{
+				Code:    http.StatusUnprocessableEntity,
+				Message: err.Error(),
+			}, nil
+		}
+		result.Warnings = append(result.Warnings, strings.Split(err.Error(), "\n")...)
+	}
+	result.Data = data
+
+	// Alternatives that lost placeholders are dropped
+	alternatives := result.Alternatives[:0]
+	for _, alternative := range result.Alternatives {
+		if restored, err := m.unmask(alternative, expected); err == nil {
+			alternatives = append(alternatives, restored)
+		}
+	}
+	result.Alternatives = alternatives
+
+	return result, nil
+}
+
+// ProtectPlaceholders wraps a batch translation function so that the
+// placeholders of every text are masked before fn is called and restored
+// in its translations. With opts.Strict a lost placeholder fails the batch,
+// otherwise it is reported to opts.Warn.
+func ProtectPlaceholders(fn func(texts []string) ([]string, error), opts PlaceholderOptions) func(texts []string) ([]string, error) {
+	return func(texts []string) ([]string, error) {
+		m := &masker{}
+		masked := make([]string, len(texts))
+		for i, text := range texts {
+			var err error
+			if masked[i], err = maskPlaceholders(m, text, opts); err != nil {
+				return nil, err
+			}
+		}
+
+		translations, err := fn(masked)
+		if err != nil {
+			return nil, err
+		}
+		if len(translations) != len(texts) {
+			return nil, fmt.Errorf("expected %d translations, got %d", len(texts), len(translations))
+		}
+
+		for i := range translations {
+			restored, err := m.unmask(translations[i], sentinelsIn(masked[i]))
+			if err != nil {
+				if opts.Strict {
+					return nil, err
+				}
+				if opts.Warn != nil {
+					for _, warning := range strings.Split(err.Error(), "\n") {
+						opts.Warn(warning)
+					}
+				}
+			}
+			translations[i] = restored
+		}
+		return translations, nil
+	}
+}
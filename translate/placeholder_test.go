@@ -0,0 +1,191 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 17:20:14
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 17:20:14
+ * @FilePath: /DeepLX/translate/placeholder_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaskPlaceholders(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		opts   PlaceholderOptions
+		masked string
+		spans  []string
+	}{
+		{"printf", "%s has %1$d items, 100%% done, %(name)s and %.2f", PlaceholderOptions{Styles: []string{PlaceholderPrintf}},
+			"⟦0⟧ has ⟦1⟧ items, 100⟦2⟧ done, ⟦3⟧ and ⟦4⟧", []string{"%s", "%1$d", "%%", "%(name)s", "%.2f"}},
+		{"printf objective-c", "Hello %@", PlaceholderOptions{Styles: []string{PlaceholderPrintf}},
+			"Hello ⟦0⟧", []string{"%@"}},
+		{"icu simple arguments", "Hello {name}, you paid {amount, number}", PlaceholderOptions{Styles: []string{PlaceholderICU}},
+			"Hello ⟦0⟧, you paid ⟦1⟧", []string{"{name}", "{amount, number}"}},
+		{"icu plural keeps case texts", "{count, plural, one {# file} other {# files}}", PlaceholderOptions{Styles: []string{PlaceholderICU}},
+			"⟦0⟧ file⟦1⟧ files⟦2⟧", []string{"{count, plural, one {#", "} other {#", "}}"}},
+		{"icu ignores prose braces", "Use {braces like this} freely", PlaceholderOptions{Styles: []string{PlaceholderICU}},
+			"Use {braces like this} freely", nil},
+		{"mustache", "Hi {{user.name}}, {{{html}}}", PlaceholderOptions{Styles: []string{PlaceholderMustache}},
+			"Hi ⟦0⟧, ⟦1⟧", []string{"{{user.name}}", "{{{html}}}"}},
+		{"mustache left to mustache style", "Hi {{name}}", PlaceholderOptions{Styles: []string{PlaceholderICU}},
+			"Hi {{name}}", nil},
+		{"custom pattern", "Go to :page now, %s", PlaceholderOptions{Styles: []string{PlaceholderMustache}, Patterns: []string{`:\w+`}},
+			"Go to ⟦0⟧ now, %s", []string{":page"}},
+		{"all styles by default", "{n} %d {{x}}", PlaceholderOptions{},
+			"⟦0⟧ ⟦1⟧ ⟦2⟧", []string{"{n}", "%d", "{{x}}"}},
+		{"adjacent spans merged", "%s%d", PlaceholderOptions{},
+			"⟦0⟧", []string{"%s%d"}},
+		{"sentinels in the text protected", "Keep ⟦3⟧ as is", PlaceholderOptions{},
+			"Keep ⟦0⟧ as is", []string{"⟦3⟧"}},
+	}
+	for _, tt := range tests {
+		m := &masker{}
+		masked, err := maskPlaceholders(m, tt.text, tt.opts)
+		if err != nil {
+			t.Errorf("%s: maskPlaceholders() error = %v", tt.name, err)
+			continue
+		}
+		if masked != tt.masked || !reflect.DeepEqual(m.spans, tt.spans) {
+			t.Errorf("%s: maskPlaceholders(%q) = %q with spans %q, want %q with %q", tt.name, tt.text, masked, m.spans, tt.masked, tt.spans)
+		}
+		if restored, err := m.unmask(masked, sentinelsIn(masked)); err != nil || restored != tt.text {
+			t.Errorf("%s: unmask() = %q, %v, want the original text", tt.name, restored, err)
+		}
+	}
+}
+
+func TestMaskPlaceholdersErrors(t *testing.T) {
+	for _, opts := range []PlaceholderOptions{
+		{Styles: []string{"jinja"}},
+		{Patterns: []string{"("}},
+	} {
+		if _, err := maskPlaceholders(&masker{}, "text", opts); err == nil {
+			t.Errorf("maskPlaceholders() with %+v succeeded, want an error", opts)
+		}
+	}
+}
+
+// rewriteTranslator translates by calling rewrite on the text, its
+// alternatives are templates where TEXT stands for the text sent
+type rewriteTranslator struct {
+	rewrite      func(text string) string
+	alternatives []string
+	requests     []string
+}
+
+func (t *rewriteTranslator) Name() string { return "rewrite" }
+
+func (t *rewriteTranslator) Translate(ctx context.Context, req Request) (DeepLXTranslationResult, error) {
+	t.requests = append(t.requests, req.Text)
+	var alternatives []string
+	for _, alternative := range t.alternatives {
+		alternatives = append(alternatives, strings.ReplaceAll(alternative, "TEXT", req.Text))
+	}
+	return DeepLXTranslationResult{
+		Code:         http.StatusOK,
+		Data:         t.rewrite(req.Text),
+		Alternatives: alternatives,
+	}, nil
+}
+
+func TestTranslateWithPlaceholders(t *testing.T) {
+	text := "Hello {name}, you have %d messages"
+	tests := []struct {
+		name     string
+		rewrite  func(string) string
+		strict   bool
+		code     int
+		data     string
+		warnings int
+	}{
+		{"restored", func(s string) string { return strings.Replace(s, "Hello", "Hallo", 1) }, false,
+			http.StatusOK, "Hallo {name}, you have %d messages", 0},
+		{"missing", func(s string) string { return strings.Replace(s, "⟦0⟧", "", 1) }, false,
+			http.StatusOK, "Hello , you have %d messages", 1},
+		{"duplicated", func(s string) string { return s + " ⟦1⟧" }, false,
+			http.StatusOK, text + " %d", 1},
+		{"missing and duplicated", func(s string) string { return strings.Replace(s, "⟦0⟧", "⟦1⟧", 1) }, false,
+			http.StatusOK, "Hello %d, you have %d messages", 2},
+		{"strict missing", func(s string) string { return strings.Replace(s, "⟦1⟧", "", 1) }, true,
+			http.StatusUnprocessableEntity, "", 0},
+		{"strict duplicated", func(s string) string { return s + "⟦0⟧" }, true,
+			http.StatusUnprocessableEntity, "", 0},
+	}
+	for _, tt := range tests {
+		translator := &rewriteTranslator{rewrite: tt.rewrite, alternatives: []string{"TEXT!", "lost"}}
+		result, err := TranslateWithPlaceholders(context.Background(), translator, Request{Text: text}, PlaceholderOptions{Strict: tt.strict})
+		if err != nil {
+			t.Errorf("%s: TranslateWithPlaceholders() error = %v", tt.name, err)
+			continue
+		}
+		if translator.requests[0] != "Hello ⟦0⟧, you have ⟦1⟧ messages" {
+			t.Errorf("%s: sent %q upstream", tt.name, translator.requests[0])
+		}
+		if result.Code != tt.code || result.Data != tt.data || len(result.Warnings) != tt.warnings {
+			t.Errorf("%s: result = %+v, want code %d, data %q and %d warnings", tt.name, result, tt.code, tt.data, tt.warnings)
+		}
+		if tt.code == http.StatusOK && !reflect.DeepEqual(result.Alternatives, []string{text + "!"}) {
+			t.Errorf("%s: alternatives = %q, want only the one keeping its placeholders", tt.name, result.Alternatives)
+		}
+	}
+
+	result, err := TranslateWithPlaceholders(context.Background(), &rewriteTranslator{}, Request{Text: text}, PlaceholderOptions{Styles: []string{"jinja"}})
+	if err != nil || result.Code != http.StatusBadRequest {
+		t.Errorf("unknown style: result = %+v, %v, want %d", result, err, http.StatusBadRequest)
+	}
+}
+
+func TestProtectPlaceholders(t *testing.T) {
+	var sent []string
+	translate := func(texts []string) ([]string, error) {
+		sent = texts
+		translations := make([]string, len(texts))
+		for i, text := range texts {
+			translations[i] = strings.ToUpper(text)
+		}
+		translations[1] = strings.Replace(translations[1], "⟦1⟧", "", 1)
+		return translations, nil
+	}
+
+	var warnings []string
+	got, err := ProtectPlaceholders(translate, PlaceholderOptions{
+		Warn: func(warning string) { warnings = append(warnings, warning) },
+	})([]string{"hi {name}", "bye %s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hi ⟦0⟧", "bye ⟦1⟧"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+	if want := []string{"HI {name}", "BYE "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("translations = %q, want %q", got, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"%s" is missing`) {
+		t.Errorf("warnings = %q, want the missing placeholder", warnings)
+	}
+
+	_, err = ProtectPlaceholders(translate, PlaceholderOptions{Strict: true})([]string{"hi {name}", "bye %s"})
+	if err == nil || !strings.Contains(err.Error(), `"%s" is missing`) {
+		t.Errorf("strict: error = %v, want the missing placeholder", err)
+	}
+
+	failure := errors.New("upstream down")
+	_, err = ProtectPlaceholders(func([]string) ([]string, error) { return nil, failure }, PlaceholderOptions{})([]string{"x"})
+	if !errors.Is(err, failure) {
+		t.Errorf("error = %v, want the error of the batch", err)
+	}
+}
@@ -0,0 +1,21 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 10:26:51
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 10:26:51
+ * @FilePath: /DeepLX/translate/trace.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"go.opentelemetry.io/otel"
+)
+
+// tracer creates the spans of the translate package. Until a tracer
+// provider is installed, they are no-ops.
+var tracer = otel.Tracer("github.com/OwO-Network/DeepLX/translate")
@@ -2,7 +2,7 @@
  * @Author: Vincent Young
  * @Date: 2024-09-16 11:59:24
  * @LastEditors: Vincent Yang
- * @LastEditTime: 2025-07-13 23:09:49
+ * @LastEditTime: 2026-10-20 00:24:51
  * @FilePath: /DeepLX/translate/translate.go
  * @Telegram: https://t.me/missuo
  * @GitHub: https://github.com/missuo
@@ -14,42 +14,36 @@ package translate
 
 import (
 	"bytes"
-	"compress/flate"
-	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"strings"
+	"time"
+	"unicode/utf8"
 
-	"github.com/abadojack/whatlanggo"
 	"github.com/imroc/req/v3"
 
-	"github.com/andybalholm/brotli"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/OwO-Network/DeepLX/metrics"
 )
 
+// maxSnippetLength is how much of an invalid upstream response is logged
+const maxSnippetLength = 256
+
 // makeRequestWithBody makes an HTTP request with pre-formatted body using minimal headers
-func makeRequestWithBody(postStr string, proxyURL string, dlSession string) (gjson.Result, error) {
-	urlFull := "https://www2.deepl.com/jsonrpc"
+func makeRequestWithBody(ctx context.Context, postStr string, proxyURL string, dlSession string) (gjson.Result, error) {
+	urlFull, headers := upstreamRequest()
 
 	// Create a new req client
 	client := req.C().SetTLSFingerprintRandomized()
 
-	// Set headers to simulate browser request
-	headers := http.Header{
-		"Content-Type":    []string{"application/json"},
-		"Accept":          []string{"*/*"},
-		"Accept-Language": []string{"en-US,en;q=0.9"},
-		"Accept-Encoding": []string{"gzip, deflate, br, zstd"},
-		"Origin":          []string{"https://www.deepl.com"},
-		"Referer":         []string{"https://www.deepl.com/"},
-		"Sec-Fetch-Dest":  []string{"empty"},
-		"Sec-Fetch-Mode":  []string{"cors"},
-		"Sec-Fetch-Site":  []string{"same-site"},
-		"User-Agent":      []string{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36 Edg/141.0.0.0"},
-	}
-
 	if dlSession != "" {
 		headers.Set("Cookie", "dl_session="+dlSession)
 	}
@@ -63,20 +57,61 @@ func makeRequestWithBody(postStr string, proxyURL string, dlSession string) (gjs
 		client.SetProxyURL(proxy.String())
 	}
 
+	ctx, span := tracer.Start(ctx, "deepl.jsonrpc", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("url.full", urlFull),
+		attribute.Bool("deeplx.proxied", proxyURL != ""),
+		attribute.Bool("deeplx.dl_session", dlSession != ""),
+	)
+
 	// Make the request
 	r := client.R()
 	r.Headers = headers
+	start := time.Now()
 	resp, err := r.
+		SetContext(ctx).
 		SetBody(bytes.NewReader([]byte(postStr))).
 		Post(urlFull)
 
 	if err != nil {
+		metrics.UpstreamRequest(proxyURL, dlSession, 0, time.Since(start))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return gjson.Result{}, err
 	}
+	metrics.UpstreamRequest(proxyURL, dlSession, resp.StatusCode, time.Since(start))
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode != 200 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	body, decodeErr := decodeBody(raw, contentEncoding)
+	valid := decodeErr == nil && gjson.ValidBytes(body)
+
+	// Surface JSON-RPC error objects, whatever the status code
+	if valid {
+		if upstreamErr := parseUpstreamError(gjson.ParseBytes(body)); upstreamErr != nil {
+			if errors.Is(upstreamErr, ErrRateLimited) {
+				if resp.StatusCode != http.StatusTooManyRequests {
+					metrics.RateLimited(proxyURL, dlSession)
+				}
+				coolDown(proxyURL, dlSession)
+			}
+			span.SetStatus(codes.Error, upstreamErr.Error())
+			return gjson.Result{}, upstreamErr
+		}
+	}
 
 	// Check for blocked status like TypeScript version
-	if resp.StatusCode == 429 {
-		return gjson.Result{}, fmt.Errorf("too many requests, your IP has been blocked by DeepL temporarily, please don't request it frequently in a short time")
+	if resp.StatusCode == http.StatusTooManyRequests {
+		coolDown(proxyURL, dlSession)
+		return gjson.Result{}, fmt.Errorf("%w, your IP has been blocked by DeepL temporarily, please don't request it frequently in a short time", ErrRateLimited)
 	}
 
 	// Check for other error status codes
@@ -84,31 +119,21 @@ func makeRequestWithBody(postStr string, proxyURL string, dlSession string) (gjs
 		return gjson.Result{}, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
 	}
 
-	var bodyReader io.Reader
-	contentEncoding := resp.Header.Get("Content-Encoding")
-	switch contentEncoding {
-	case "br":
-		bodyReader = brotli.NewReader(resp.Body)
-	case "gzip":
-		bodyReader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			return gjson.Result{}, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-	case "deflate":
-		bodyReader = flate.NewReader(resp.Body)
-	default:
-		bodyReader = resp.Body
+	if decodeErr != nil {
+		slog.DebugContext(ctx, "Failed to decode upstream response", "content_encoding", contentEncoding, "body", bodySnippet(raw, maxSnippetLength), "error", decodeErr)
+		return gjson.Result{}, decodeErr
 	}
-
-	body, err := io.ReadAll(bodyReader)
-	if err != nil {
-		return gjson.Result{}, fmt.Errorf("failed to read response body: %w", err)
+	if !valid {
+		slog.DebugContext(ctx, "Invalid upstream response", "content_encoding", contentEncoding, "body", bodySnippet(body, maxSnippetLength))
+		span.SetStatus(codes.Error, ErrInvalidResponse.Error())
+		return gjson.Result{}, ErrInvalidResponse
 	}
 	return gjson.ParseBytes(body), nil
 }
 
-// TranslateByDeepLX performs translation using DeepL API
-func TranslateByDeepLX(sourceLang, targetLang, text string, tagHandling string, proxyURL string, dlSession string) (DeepLXTranslationResult, error) {
+// TranslateByDeepLX performs translation using DeepL API. sourceLangHints
+// are the likely source languages when sourceLang is auto.
+func TranslateByDeepLX(ctx context.Context, sourceLang string, sourceLangHints []string, targetLang, text string, tagHandling string, proxyURL string, dlSession string) (DeepLXTranslationResult, error) {
 	if text == "" {
 		return DeepLXTranslationResult{
 			Code:    http.StatusNotFound,
@@ -118,12 +143,31 @@ func TranslateByDeepLX(sourceLang, targetLang, text string, tagHandling string,
 
 	// Get detected language if source language is auto
 	if sourceLang == "auto" || sourceLang == "" {
-		sourceLang = strings.ToUpper(whatlanggo.DetectLang(text).Iso6391())
+		sourceLang = detectLang(ctx, text, sourceLangHints)
+	}
+
+	// Translate only the prose of Markdown documents
+	if tagHandling == "markdown" {
+		return translateMarkdown(ctx, sourceLang, targetLang, text, proxyURL, dlSession)
 	}
 
+	// Split oversized texts so that nothing gets lost upstream
+	maxLength, concurrency := getChunking()
+	if chunks := splitText(text, maxLength, tagHandling); len(chunks) > 1 {
+		return translateChunks(ctx, sourceLang, targetLang, chunks, tagHandling, proxyURL, dlSession, concurrency)
+	}
+
+	return translateText(ctx, sourceLang, targetLang, text, tagHandling, proxyURL, dlSession)
+}
+
+// handleTexts sends the text items upstream in a single LMT_handle_texts request
+func handleTexts(ctx context.Context, sourceLang, targetLang string, items []TextItem, proxyURL string, dlSession string) (int64, gjson.Result, error) {
 	// Prepare translation request using new LMT_handle_texts method
 	id := getRandomNumber()
-	iCount := getICount(text)
+	var iCount int64
+	for _, item := range items {
+		iCount += getICount(item.Text)
+	}
 	timestamp := getTimeStamp(iCount)
 
 	postData := &PostData{
@@ -136,10 +180,7 @@ func TranslateByDeepLX(sourceLang, targetLang, text string, tagHandling string,
 				SourceLangUserSelected: sourceLang,
 				TargetLang:             targetLang,
 			},
-			Texts: []TextItem{{
-				Text:                text,
-				RequestAlternatives: 3,
-			}},
+			Texts:     items,
 			Timestamp: timestamp,
 		},
 	}
@@ -149,12 +190,28 @@ func TranslateByDeepLX(sourceLang, targetLang, text string, tagHandling string,
 	postStr = handlerBodyMethod(id, postStr)
 
 	// Make translation request
-	result, err := makeRequestWithBody(postStr, proxyURL, dlSession)
+	result, err := makeRequestWithBody(ctx, postStr, proxyURL, dlSession)
+	if err == nil {
+		if detectedLang := result.Get("result.lang").String(); detectedLang != "" {
+			sourceLang = detectedLang
+		}
+		characters := 0
+		for _, item := range items {
+			characters += utf8.RuneCountInString(item.Text)
+		}
+		countCharacters(sourceLang, targetLang, characters)
+	}
+	return id, result, err
+}
+
+// translateText sends text upstream in a single LMT_handle_texts request
+func translateText(ctx context.Context, sourceLang, targetLang, text string, tagHandling string, proxyURL string, dlSession string) (DeepLXTranslationResult, error) {
+	id, result, err := handleTexts(ctx, sourceLang, targetLang, []TextItem{{
+		Text:                text,
+		RequestAlternatives: 3,
+	}}, proxyURL, dlSession)
 	if err != nil {
-		return DeepLXTranslationResult{
-			Code:    http.StatusServiceUnavailable,
-			Message: err.Error(),
-		}, nil
+		return errorResult(err), nil
 	}
 
 	// Process translation results using new format
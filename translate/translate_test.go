@@ -0,0 +1,32 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 14:31:07
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 14:31:07
+ * @FilePath: /DeepLX/translate/translate_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OwO-Network/DeepLX/fakedeepl"
+)
+
+// newFakeUpstream starts a fake DeepL upstream and sends the upstream
+// requests of the test there
+func newFakeUpstream(t *testing.T) *fakedeepl.Server {
+	t.Helper()
+	upstream := fakedeepl.New()
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+	SetUpstream(Upstream{URL: server.URL + "/jsonrpc"})
+	t.Cleanup(func() { SetUpstream(Upstream{}) })
+	return upstream
+}
@@ -28,6 +28,8 @@ type CommonJobParams struct {
 	AdvancedMode    bool   `json:"advancedMode"`
 	TextType        string `json:"textType"`
 	RegionalVariant string `json:"regionalVariant,omitempty"`
+	WritingStyle    string `json:"writingStyle,omitempty"` // DeepL Write only
+	Tone            string `json:"tone,omitempty"`         // DeepL Write only
 }
 
 // Sentence represents a sentence in the translation request
@@ -76,9 +78,17 @@ type PostData struct {
 	Params  Params `json:"params"`
 }
 
+// JobsPostData represents a LMT_handle_jobs request, used by DeepL Write
+type JobsPostData struct {
+	Jsonrpc string       `json:"jsonrpc"`
+	Method  string       `json:"method"`
+	ID      int64        `json:"id"`
+	Params  LegacyParams `json:"params"`
+}
+
 // TextResponse represents a single text response
 type TextResponse struct {
-	Text         string   `json:"text"`
+	Text         string `json:"text"`
 	Alternatives []struct {
 		Text string `json:"text"`
 	} `json:"alternatives"`
@@ -89,8 +99,8 @@ type TranslationResponse struct {
 	Jsonrpc string `json:"jsonrpc"`
 	ID      int64  `json:"id"`
 	Result  struct {
-		Lang  string          `json:"lang"`
-		Texts []TextResponse  `json:"texts"`
+		Lang  string         `json:"lang"`
+		Texts []TextResponse `json:"texts"`
 	} `json:"result"`
 }
 
@@ -127,9 +137,11 @@ type DeepLXTranslationResult struct {
 	Code         int      `json:"code"`
 	ID           int64    `json:"id"`
 	Message      string   `json:"message,omitempty"`
-	Data         string   `json:"data"`         // The primary translated text
-	Alternatives []string `json:"alternatives"` // Other possible translations
+	Data         string   `json:"data"`            // The primary translated text
+	Alternatives []string `json:"alternatives"`    // Other possible translations
+	Texts        []string `json:"texts,omitempty"` // Translations of every text of a batch
 	SourceLang   string   `json:"source_lang"`
 	TargetLang   string   `json:"target_lang"`
 	Method       string   `json:"method"`
+	Warnings     []string `json:"warnings,omitempty"`
 }
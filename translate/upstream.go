@@ -0,0 +1,93 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 13:40:09
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 13:40:09
+ * @FilePath: /DeepLX/translate/upstream.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"net/http"
+	"sync"
+)
+
+const (
+	// DefaultUpstreamURL is the DeepL JSON-RPC endpoint
+	DefaultUpstreamURL = "https://www2.deepl.com/jsonrpc"
+	// DefaultUserAgent is the browser user agent sent upstream
+	DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36 Edg/141.0.0.0"
+)
+
+// Upstream configures the JSON-RPC endpoint requests are sent to, like a
+// local stub in tests or an internal relay
+type Upstream struct {
+	// URL of the JSON-RPC endpoint, DefaultUpstreamURL when empty
+	URL string
+	// UserAgent sent upstream, DefaultUserAgent when empty
+	UserAgent string
+	// Headers added to or replacing the browser headers sent upstream. An
+	// empty value removes the header.
+	Headers map[string]string
+}
+
+var (
+	upstreamMu sync.RWMutex
+	upstream   Upstream
+)
+
+// SetUpstream configures the JSON-RPC endpoint, user agent and headers of
+// upstream requests. The zero Upstream restores the defaults.
+func SetUpstream(u Upstream) {
+	headers := make(map[string]string, len(u.Headers))
+	for name, value := range u.Headers {
+		headers[name] = value
+	}
+	u.Headers = headers
+
+	upstreamMu.Lock()
+	upstream = u
+	upstreamMu.Unlock()
+}
+
+// upstreamRequest returns the URL and headers of an upstream request
+func upstreamRequest() (string, http.Header) {
+	upstreamMu.RLock()
+	defer upstreamMu.RUnlock()
+
+	urlFull := upstream.URL
+	if urlFull == "" {
+		urlFull = DefaultUpstreamURL
+	}
+	userAgent := upstream.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+
+	// Set headers to simulate browser request
+	headers := http.Header{
+		"Content-Type":    []string{"application/json"},
+		"Accept":          []string{"*/*"},
+		"Accept-Language": []string{"en-US,en;q=0.9"},
+		"Accept-Encoding": []string{"gzip, deflate, br, zstd"},
+		"Origin":          []string{"https://www.deepl.com"},
+		"Referer":         []string{"https://www.deepl.com/"},
+		"Sec-Fetch-Dest":  []string{"empty"},
+		"Sec-Fetch-Mode":  []string{"cors"},
+		"Sec-Fetch-Site":  []string{"same-site"},
+		"User-Agent":      []string{userAgent},
+	}
+	for name, value := range upstream.Headers {
+		if value == "" {
+			headers.Del(name)
+		} else {
+			headers.Set(name, value)
+		}
+	}
+	return urlFull, headers
+}
@@ -43,7 +43,7 @@ func getTimeStamp(iCount int64) int64 {
 }
 
 // formatPostString formats the request JSON string with specific spacing rules
-func formatPostString(postData *PostData) string {
+func formatPostString(postData any) string {
 	postBytes, _ := json.Marshal(postData)
 	postStr := string(postBytes)
 	return postStr
@@ -0,0 +1,109 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-20 15:02:48
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-20 15:02:48
+ * @FilePath: /DeepLX/translate/utils_test.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetICount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"", 0},
+		{"Hello", 0},
+		{"This is it", 3},
+		{"iiii", 4},
+		{"IMPORTANT", 0},
+		{"Ïl ìs naïve", 0},
+	}
+	for _, tt := range tests {
+		if got := getICount(tt.text); got != tt.want {
+			t.Errorf("getICount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGetTimeStamp(t *testing.T) {
+	before := time.Now().UnixMilli()
+	got := getTimeStamp(0)
+	after := time.Now().UnixMilli()
+	if got < before || got > after {
+		t.Errorf("getTimeStamp(0) = %d, want the current time between %d and %d", got, before, after)
+	}
+
+	for _, iCount := range []int64{1, 2, 3, 7, 100} {
+		before := time.Now().UnixMilli()
+		got := getTimeStamp(iCount)
+		after := time.Now().UnixMilli()
+		if got%(iCount+1) != 0 {
+			t.Errorf("getTimeStamp(%d) = %d, want a multiple of %d", iCount, got, iCount+1)
+		}
+		if got <= before-iCount-1 || got > after+iCount+1 {
+			t.Errorf("getTimeStamp(%d) = %d, want it within %d of %d..%d", iCount, got, iCount+1, before, after)
+		}
+	}
+}
+
+func TestHandlerBodyMethod(t *testing.T) {
+	body := `{"jsonrpc":"2.0","method":"LMT_handle_texts","params":{"method":"x"}}`
+	tests := []struct {
+		random int64
+		want   string
+	}{
+		// (random+5)%29 == 0
+		{24, `"method" : "`},
+		{53, `"method" : "`},
+		// (random+3)%13 == 0
+		{10, `"method" : "`},
+		{23, `"method" : "`},
+		{1, `"method": "`},
+		{100000000, `"method": "`},
+	}
+	for _, tt := range tests {
+		got := handlerBodyMethod(tt.random, body)
+		if want := strings.Replace(body, `"method":"`, tt.want, 1); got != want {
+			t.Errorf("handlerBodyMethod(%d) = %s, want %s", tt.random, got, want)
+		}
+	}
+}
+
+func TestGetRandomNumber(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		n := getRandomNumber()
+		if n%1000 != 0 || n < 100000000 || n >= 199999000 {
+			t.Fatalf("getRandomNumber() = %d, want a multiple of 1000 in [100000000, 199999000)", n)
+		}
+	}
+}
+
+func TestFormatPostString(t *testing.T) {
+	postData := &PostData{
+		Jsonrpc: "2.0",
+		Method:  "LMT_handle_texts",
+		ID:      123000,
+		Params: Params{
+			Splitting: "newlines",
+			Lang:      Lang{SourceLangUserSelected: "EN", TargetLang: "DE"},
+			Texts:     []TextItem{{Text: "Hi", RequestAlternatives: 3}},
+			Timestamp: 1700000000001,
+		},
+	}
+	want := `{"jsonrpc":"2.0","method":"LMT_handle_texts","id":123000,"params":{"splitting":"newlines","lang":{"source_lang_user_selected":"EN","target_lang":"DE"},"texts":[{"text":"Hi","requestAlternatives":3}],"timestamp":1700000000001}}`
+	if got := formatPostString(postData); got != want {
+		t.Errorf("formatPostString() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * @Author: Vincent Yang
+ * @Date: 2026-10-22 10:14:37
+ * @LastEditors: Vincent Yang
+ * @LastEditTime: 2026-10-22 10:14:37
+ * @FilePath: /DeepLX/translate/write.go
+ * @Telegram: https://t.me/missuo
+ * @GitHub: https://github.com/missuo
+ *
+ * Copyright © 2024 by Vincent, All Rights Reserved.
+ */
+
+package translate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// writeBeams is the number of rephrasings asked for per text, the first one
+// is the improvement and the others its alternatives
+const writeBeams = 4
+
+// WritingStyles are the writing styles of DeepL Write. The prefer_ variants
+// fall back to the default style for languages without the style.
+var WritingStyles = []string{
+	"default", "simple", "business", "academic", "casual",
+	"prefer_simple", "prefer_business", "prefer_academic", "prefer_casual",
+}
+
+// WritingTones are the tones of DeepL Write, with prefer_ variants like the
+// writing styles
+var WritingTones = []string{
+	"default", "enthusiastic", "friendly", "confident", "diplomatic",
+	"prefer_enthusiastic", "prefer_friendly", "prefer_confident", "prefer_diplomatic",
+}
+
+// writeLangs maps the target languages of DeepL Write to their regional
+// variant, empty for languages without one
+var writeLangs = map[string]string{
+	"DE":    "",
+	"EN":    "",
+	"EN-GB": "en-GB",
+	"EN-US": "en-US",
+	"ES":    "",
+	"FR":    "",
+	"IT":    "",
+	"PT":    "",
+	"PT-BR": "pt-BR",
+	"PT-PT": "pt-PT",
+}
+
+// Improvement is a text improved by DeepL Write
+type Improvement struct {
+	Text               string   `json:"text"`
+	Alternatives       []string `json:"alternatives"`
+	DetectedSourceLang string   `json:"detected_source_language"`
+	TargetLang         string   `json:"target_language"`
+}
+
+// Rephrase improves texts with DeepL Write in a single LMT_handle_jobs
+// request. targetLang may be empty to keep the language of the texts, and at
+// most one of style and tone may be set.
+func Rephrase(ctx context.Context, texts []string, targetLang, style, tone string, proxyURL string, dlSession string) ([]Improvement, error) {
+	switch {
+	case style != "" && tone != "":
+		return nil, fmt.Errorf("%w: writing_style and tone cannot be combined", ErrInvalidRequest)
+	case style != "" && !slices.Contains(WritingStyles, style):
+		return nil, fmt.Errorf("%w: unknown writing_style %q", ErrInvalidRequest, style)
+	case tone != "" && !slices.Contains(WritingTones, tone):
+		return nil, fmt.Errorf("%w: unknown tone %q", ErrInvalidRequest, tone)
+	}
+
+	targetLang = strings.ToUpper(targetLang)
+	if targetLang == "" {
+		targetLang = DetectLanguage(strings.Join(texts, "\n"), nil).SourceLang
+	}
+	regionalVariant, ok := writeLangs[targetLang]
+	if !ok {
+		return nil, fmt.Errorf("%w: DeepL Write does not support %q", ErrUnsupportedLanguage, targetLang)
+	}
+	lang, _, _ := strings.Cut(targetLang, "-")
+
+	id := getRandomNumber()
+	var iCount int64
+	jobs := make([]Job, len(texts))
+	for i, text := range texts {
+		iCount += getICount(text)
+		jobs[i] = Job{
+			Kind:               "default",
+			PreferredNumBeams:  writeBeams,
+			RawEnContextBefore: []string{},
+			RawEnContextAfter:  []string{},
+			Sentences:          []Sentence{{Text: text, ID: i}},
+		}
+	}
+	postData := &JobsPostData{
+		Jsonrpc: "2.0",
+		Method:  "LMT_handle_jobs",
+		ID:      id,
+		Params: LegacyParams{
+			CommonJobParams: CommonJobParams{
+				Mode:            "write",
+				RegionalVariant: regionalVariant,
+				WritingStyle:    style,
+				Tone:            tone,
+			},
+			Lang: Lang{
+				SourceLangUserSelected: AutoLang,
+				TargetLang:             lang,
+			},
+			Jobs:      jobs,
+			Timestamp: getTimeStamp(iCount),
+		},
+	}
+
+	postStr := handlerBodyMethod(id, formatPostString(postData))
+	result, err := makeRequestWithBody(ctx, postStr, proxyURL, dlSession)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceLang := strings.ToUpper(result.Get("result.source_lang").String())
+	translations := result.Get("result.translations").Array()
+	if len(translations) != len(texts) {
+		return nil, fmt.Errorf("%w: got %d improvements for %d texts", ErrInvalidResponse, len(translations), len(texts))
+	}
+
+	improvements := make([]Improvement, len(texts))
+	characters := 0
+	for i, translation := range translations {
+		improvements[i] = Improvement{
+			Alternatives:       []string{},
+			DetectedSourceLang: sourceLang,
+			TargetLang:         targetLang,
+		}
+		for j, beam := range translation.Get("beams").Array() {
+			var text strings.Builder
+			for _, sentence := range beam.Get("sentences").Array() {
+				text.WriteString(sentence.Get("text").String())
+			}
+			if j == 0 {
+				improvements[i].Text = text.String()
+			} else if text.Len() > 0 {
+				improvements[i].Alternatives = append(improvements[i].Alternatives, text.String())
+			}
+		}
+		if improvements[i].Text == "" {
+			return nil, fmt.Errorf("%w: no improvement for text %d", ErrInvalidResponse, i+1)
+		}
+		characters += utf8.RuneCountInString(texts[i])
+	}
+	countCharacters(sourceLang, targetLang, characters)
+	return improvements, nil
+}